@@ -40,6 +40,12 @@ func GetIntegrationTest(client yarf.Client) func(t *testing.T) {
 		t.Run("Swap", GetTestSwap(client, simple.Tuple{Val1: 1, Val2: 2}, 3))
 		t.Run("Conc", GetTestConc(client, 25))
 		t.Run("SwapWithSerializer", GetTestSwapWithSerlizer(client, simple.Tuple{Val1: 1, Val2: 2}))
+		t.Run("SwapTyped", GetTestSwapTyped(client, simple.Tuple{Val1: 1, Val2: 2}, 3))
+		t.Run("Stream", GetTestStream(client, 25, 0))
+		t.Run("StreamError", GetTestStreamError(client, 5))
+		t.Run("Duplex", GetTestDuplex(client, []int{1, 2, 3, 5, 8}))
+		t.Run("Upload", GetTestUpload(client, 10, 1000))
+		t.Run("UploadError", GetTestUploadError(client))
 	}
 }
 
@@ -51,6 +57,8 @@ func GetExtraIntegrationTest(client yarf.Client) func(t *testing.T) {
 		t.Run("GetTestGen", GetTestGen(client, length))
 		t.Run("GetTestCopy", GetTestCopy(client, length))
 		t.Run("GetTestSHA256", GetTestSHA256(client, length))
+		t.Run("GetTestStream", GetTestStream(client, 5, length/4))
+		t.Run("GetTestUpload", GetTestUpload(client, 5, length/4))
 	}
 }
 
@@ -421,6 +429,24 @@ func GetTestSwap(client yarf.Client, tuple simple.Tuple, multiplier int) func(t
 	}
 }
 
+// GetTestSwapTyped generates test for swaping values in a tuple using a typed handler
+func GetTestSwapTyped(client yarf.Client, tuple simple.Tuple, multiplier int) func(t *testing.T) {
+	return func(t *testing.T) {
+		res, err := simple.SwapTypedRequest(client, tuple, multiplier)
+
+		if err != nil {
+			t.Log("Got err", err)
+			t.Fail()
+			return
+		}
+
+		if tuple.Val1*multiplier != res.Val2 || tuple.Val2*multiplier != res.Val1 {
+			t.Log("Got response", res, "expected", simple.Tuple{Val1: tuple.Val2 * multiplier, Val2: tuple.Val1 * multiplier})
+			t.Fail()
+		}
+	}
+}
+
 // GetTestSub generates a integer param test for a specific client
 func GetTestSub(client yarf.Client, i, j int) func(t *testing.T) {
 	return func(t *testing.T) {
@@ -540,4 +566,123 @@ func GetTestConc(client yarf.Client, sleep int) func(t *testing.T) {
 			return
 		}
 	}
-}
\ No newline at end of file
+}
+// GetTestStream generates a test streaming count messages of size from the server
+func GetTestStream(client yarf.Client, count int, size int) func(t *testing.T) {
+	return func(t *testing.T) {
+		res, msg, err := simple.CountStreamRequest(client, count, size, false)
+
+		if err != nil {
+			t.Log("Got err", err)
+			t.Fail()
+			return
+		}
+
+		if len(res) != count {
+			t.Log("Got", len(res), "messages, expected", count)
+			t.Fail()
+			return
+		}
+
+		for i, v := range res {
+			if int64(i) != v {
+				t.Log("Got message", v, "expected", i)
+				t.Fail()
+			}
+		}
+
+		if int64(count) != msg.Param("res").IntOr(-1) {
+			t.Log("Got final response", msg.Param("res"), "expected", count)
+			t.Fail()
+		}
+	}
+}
+
+// GetTestStreamError generates a test of a stream that fails after count messages
+func GetTestStreamError(client yarf.Client, count int) func(t *testing.T) {
+	return func(t *testing.T) {
+		res, _, err := simple.CountStreamRequest(client, count, 0, true)
+
+		if len(res) != count {
+			t.Log("Got", len(res), "messages, expected", count)
+			t.Fail()
+		}
+
+		rerr, ok := err.(yarf.RPCError)
+
+		if !ok {
+			t.Log("Expected rpc error, got", err)
+			t.Fail()
+			return
+		}
+
+		if rerr.Status != 600 {
+			t.Log("expected 600 got,", rerr.Status)
+			t.Fail()
+		}
+	}
+}
+
+// GetTestDuplex generates a test sending and receiving messages over a bidirectional stream
+func GetTestDuplex(client yarf.Client, vals []int) func(t *testing.T) {
+	return func(t *testing.T) {
+		res, err := simple.EchoStreamRequest(client, vals)
+
+		if err != nil {
+			t.Log("Got err", err)
+			t.Fail()
+			return
+		}
+
+		if len(res) != len(vals) {
+			t.Log("Got", len(res), "messages, expected", len(vals))
+			t.Fail()
+			return
+		}
+
+		for i, v := range res {
+			if int64(vals[i]*2) != v {
+				t.Log("Got", v, "expected", vals[i]*2)
+				t.Fail()
+			}
+		}
+	}
+}
+
+// GetTestUpload generates a test streaming chunks of size to the server
+func GetTestUpload(client yarf.Client, chunks int, size int) func(t *testing.T) {
+	return func(t *testing.T) {
+		res, err := simple.UploadStreamRequest(client, chunks, size, 0)
+
+		if err != nil {
+			t.Log("Got err", err)
+			t.Fail()
+			return
+		}
+
+		if int64(chunks*size) != res {
+			t.Log("Got", res, "expected", chunks*size)
+			t.Fail()
+		}
+	}
+}
+
+// GetTestUploadError generates a test of a client stream that is failed by the server
+func GetTestUploadError(client yarf.Client) func(t *testing.T) {
+	return func(t *testing.T) {
+		_, err := simple.UploadStreamRequest(client, 1, 10, 100)
+
+		rerr, ok := err.(yarf.RPCError)
+
+		if !ok {
+			t.Log("Expected rpc error, got", err)
+			t.Fail()
+			return
+		}
+
+		if rerr.Status != 600 {
+			t.Log("expected 600 got,", rerr.Status)
+			t.Fail()
+		}
+	}
+}
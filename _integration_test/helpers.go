@@ -5,6 +5,7 @@ import (
 	"github.com/modfin/yarf"
 	"github.com/modfin/yarf/example/simple"
 	"github.com/modfin/yarf/transport/thttp"
+	"github.com/modfin/yarf/transport/tmem"
 	"github.com/modfin/yarf/transport/tnats"
 	"os"
 	"time"
@@ -63,3 +64,21 @@ func CreateNats(serializer yarf.Serializer, serverMiddleware ...yarf.Middleware)
 
 	return client, func() { serverTransport.Close() }
 }
+
+// CreateMem returns a setup using the in memory transport
+func CreateMem(serializer yarf.Serializer, serverMiddleware ...yarf.Middleware) (client yarf.Client, stop func()) {
+
+	transport, err := tmem.NewMemTransporter(tmem.Options{})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	simple.StartServerWithSerializer(transport, false, serializer, serverMiddleware...)
+
+	client = yarf.NewClient(transport)
+	client.WithProtocolSerializer(serializer)
+	client.WithSerializer(serializer)
+
+	return client, func() { transport.Close() }
+}
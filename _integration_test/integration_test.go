@@ -18,6 +18,7 @@ var transportTable = []struct {
 }{
 	{"HTTP", CreateHTTP, true},
 	{"NATS", CreateNats, false},
+	{"MEM", CreateMem, true},
 }
 
 var serializerTable = []struct {
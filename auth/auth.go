@@ -0,0 +1,97 @@
+// Package auth provides authentication and authorization of yarf requests. Client middleware attaches credentials to
+// the headers of a request, which are verified by the Authenticate server middleware, making the Principal of the
+// caller available to handlers through the context of the request. Since credentials are carried in the yarf message
+// itself, it works the same way on every transport.
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"strings"
+)
+
+// HeaderAuthorization is the header carrying the credentials of a request, on the format "<scheme> <credentials>"
+const HeaderAuthorization = "authorization"
+
+const (
+	// SchemeBearer is the scheme of bearer tokens, which JWTs are sent as
+	SchemeBearer = "Bearer"
+
+	// SchemeHMAC is the scheme of requests signed using HMAC
+	SchemeHMAC = "HMAC"
+)
+
+// ErrNoCredentials is returned by an Authenticator when the request does not carry credentials it is able to verify,
+// letting the next authenticator try
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject identifies the caller, e.g. the name of a service
+	Subject string
+
+	// Method is the kind of credentials used, "bearer", "hmac", "jwt" or "signature"
+	Method string
+
+	// Claims are the claims of a JWT, or nil for other methods
+	Claims map[string]interface{}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal of ctx, which is set by Authenticate on the context of a request
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	if ctx == nil {
+		return Principal{}, false
+	}
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Authenticator verifies the credentials of a request and returns the principal of the caller. ErrNoCredentials is
+// returned if the request carries no credentials of the kind verified by the authenticator
+type Authenticator func(request *yarf.Msg) (Principal, error)
+
+// authorization returns the credentials of the request, if they are of the scheme
+func authorization(request *yarf.Msg, scheme string) (credentials string, ok bool) {
+	value, _ := request.Headers[HeaderAuthorization].(string)
+	if len(value) <= len(scheme) || !strings.EqualFold(value[:len(scheme)], scheme) || value[len(scheme)] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(value[len(scheme)+1:]), true
+}
+
+// Authenticate is a server middleware verifying the credentials of every request using the authenticators, in order,
+// until one of them succeeds. The principal is put into the context of the request, see PrincipalFrom. Requests
+// without valid credentials are rejected with yarf.StatusUnauthorized.
+func Authenticate(authenticators ...Authenticator) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+
+		var failure error
+		for _, authenticate := range authenticators {
+			principal, err := authenticate(request)
+			if err == nil {
+				ctx := request.Context()
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				request.WithContext(WithPrincipal(ctx, principal))
+				return next()
+			}
+			if failure == nil && !errors.Is(err, ErrNoCredentials) {
+				failure = err
+			}
+		}
+
+		if failure == nil {
+			failure = ErrNoCredentials
+		}
+		return yarf.NewRPCError(yarf.StatusUnauthorized, "unauthorized, "+failure.Error())
+	}
+}
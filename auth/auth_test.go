@@ -0,0 +1,177 @@
+package auth_test
+
+import (
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/auth"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+	"time"
+)
+
+var key = []byte("0123456789abcdef0123456789abcdef")
+
+func newAuthPair(t *testing.T, serverMiddleware ...yarf.Middleware) (yarf.Server, func(clientMiddleware ...yarf.Middleware) *yarf.Client) {
+	transport, err := tmem.NewMemTransporter(tmem.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := yarf.NewServer(transport, "auth")
+	server.WithMiddleware(serverMiddleware...)
+	server.Handle("whoami", func(request *yarf.Msg, response *yarf.Msg) error {
+		principal, _ := auth.PrincipalFrom(request.Context())
+		response.SetParam("subject", principal.Subject)
+		response.SetParam("method", principal.Method)
+		return nil
+	})
+
+	return server, func(clientMiddleware ...yarf.Middleware) *yarf.Client {
+		client := yarf.NewClient(transport)
+		client.WithMiddleware(clientMiddleware...)
+		return &client
+	}
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var rpcErr yarf.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Status != status {
+		t.Errorf("expected status %d, got %v", status, err)
+	}
+}
+
+func whoami(t *testing.T, client *yarf.Client) (subject string, method string) {
+	t.Helper()
+	msg, err := client.Request("auth.whoami").WithContent("hello").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg.Param("subject").StringOr(""), msg.Param("method").StringOr("")
+}
+
+func TestBearer(t *testing.T) {
+	_, newClient := newAuthPair(t, auth.Authenticate(auth.BearerTokens(map[string]string{"secret": "billing"})))
+
+	subject, method := whoami(t, newClient(auth.Bearer("secret")))
+	if subject != "billing" || method != "bearer" {
+		t.Errorf("expected principal billing by bearer, got %s by %s", subject, method)
+	}
+
+	err := newClient(auth.Bearer("wrong")).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+
+	err = newClient().Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+}
+
+func TestHMAC(t *testing.T) {
+	_, newClient := newAuthPair(t, auth.Authenticate(auth.HMACKeys(map[string][]byte{"billing": key}, time.Minute)))
+
+	subject, method := whoami(t, newClient(auth.HMAC("billing", key)))
+	if subject != "billing" || method != "hmac" {
+		t.Errorf("expected principal billing by hmac, got %s by %s", subject, method)
+	}
+
+	tamper := func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetBinaryContent([]byte("tampered"))
+		return next()
+	}
+	err := newClient(auth.HMAC("billing", key), tamper).Request("auth.whoami").WithContent("hello").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+
+	err = newClient(auth.HMAC("billing", []byte("other key"))).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+
+	stale := func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetHeader(auth.HeaderTimestamp, "1")
+		return next()
+	}
+	err = newClient(auth.HMAC("billing", key), stale).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+}
+
+func TestJWT(t *testing.T) {
+	options := auth.JWTOptions{Key: key, Subject: "billing", Issuer: "issuer", Audience: "auth"}
+	_, newClient := newAuthPair(t, auth.Authenticate(
+		auth.BearerTokens(map[string]string{"secret": "static"}),
+		auth.JWTVerifier(auth.JWTOptions{Key: key, Issuer: "issuer", Audience: "auth"}),
+	))
+
+	subject, method := whoami(t, newClient(auth.JWT(options)))
+	if subject != "billing" || method != "jwt" {
+		t.Errorf("expected principal billing by jwt, got %s by %s", subject, method)
+	}
+
+	subject, _ = whoami(t, newClient(auth.Bearer("secret")))
+	if subject != "static" {
+		t.Errorf("expected bearer tokens next to jwt, got %s", subject)
+	}
+
+	other := options
+	other.Key = []byte("other key")
+	err := newClient(auth.JWT(other)).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+
+	other = options
+	other.Audience = "other"
+	err = newClient(auth.JWT(other)).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+
+	expired, _ := auth.SignJWT(key, map[string]interface{}{"sub": "billing", "iss": "issuer", "aud": "auth", "exp": time.Now().Add(-time.Hour).Unix()})
+	err = newClient(auth.Bearer(expired)).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+}
+
+func TestJWTExpiry(t *testing.T) {
+	noExpiry, _ := auth.SignJWT(key, map[string]interface{}{"sub": "billing"})
+	invalidExpiry, _ := auth.SignJWT(key, map[string]interface{}{"sub": "billing", "exp": "never"})
+
+	_, newClient := newAuthPair(t, auth.Authenticate(auth.JWTVerifier(auth.JWTOptions{Key: key})))
+	err := newClient(auth.Bearer(noExpiry)).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+	err = newClient(auth.Bearer(invalidExpiry)).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+
+	_, newClient = newAuthPair(t, auth.Authenticate(auth.JWTVerifier(auth.JWTOptions{Key: key, AllowNoExpiry: true})))
+	subject, _ := whoami(t, newClient(auth.Bearer(noExpiry)))
+	if subject != "billing" {
+		t.Errorf("expected a token without exp to be accepted when allowed, got %s", subject)
+	}
+	err = newClient(auth.Bearer(invalidExpiry)).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusUnauthorized)
+}
+
+func TestAuthorize(t *testing.T) {
+	server, newClient := newAuthPair(t,
+		auth.Authenticate(auth.BearerTokens(map[string]string{"a": "billing", "b": "reports"})),
+		auth.Authorize(auth.Functions(map[string]auth.Policy{
+			"auth.whoami": auth.Subjects("billing", "reports"),
+			"auth.charge": auth.Subjects("billing"),
+		})),
+	)
+	server.Handle("charge", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+	server.Handle("admin", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+
+	billing, reports := newClient(auth.Bearer("a")), newClient(auth.Bearer("b"))
+
+	if err := billing.Request("auth.charge").Done(); err != nil {
+		t.Error(err)
+	}
+	if err := reports.Request("auth.whoami").Done(); err != nil {
+		t.Error(err)
+	}
+	expectStatus(t, reports.Request("auth.charge").Done(), yarf.StatusForbidden)
+	expectStatus(t, billing.Request("auth.admin").Done(), yarf.StatusForbidden)
+
+	// The policy shall apply to the function dispatched to, not the one named by the header sent by the client
+	spoof := func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetHeader(yarf.HeaderFunction, "auth.whoami")
+		return next()
+	}
+	expectStatus(t, newClient(auth.Bearer("b"), spoof).Request("auth.charge").Done(), yarf.StatusForbidden)
+}
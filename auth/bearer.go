@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/modfin/yarf"
+)
+
+// Bearer is a client middleware attaching a static bearer token to every request
+func Bearer(token string) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetHeader(HeaderAuthorization, SchemeBearer+" "+token)
+		return next()
+	}
+}
+
+// BearerTokens is an Authenticator accepting the bearer tokens given, mapped to the subject of the principal
+func BearerTokens(tokens map[string]string) Authenticator {
+	return func(request *yarf.Msg) (Principal, error) {
+		token, ok := authorization(request, SchemeBearer)
+		if !ok {
+			return Principal{}, ErrNoCredentials
+		}
+
+		// Every token is compared, in constant time, not to reveal which of them is closest to the one given
+		var subject string
+		var found bool
+		for t, s := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				subject, found = s, true
+			}
+		}
+		if !found {
+			return Principal{}, errors.New("invalid bearer token")
+		}
+		return Principal{Subject: subject, Method: "bearer"}, nil
+	}
+}
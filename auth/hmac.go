@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/modfin/yarf"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderTimestamp is the time a HMAC signed request was sent, in unix milliseconds
+const HeaderTimestamp = "auth-timestamp"
+
+// hmacSignature signs the function, request id, timestamp and content of the request, binding the signature to the request
+func hmacSignature(key []byte, request *yarf.Msg, timestamp string) []byte {
+	function, _ := request.Function()
+	id, _ := request.RequestID()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(function + "\n" + id + "\n" + timestamp + "\n"))
+	mac.Write(request.Content)
+	return mac.Sum(nil)
+}
+
+// HMAC is a client middleware signing every request with the shared key, identified by keyID, using HMAC-SHA256 over
+// the function, request id, timestamp and content of the request. It shall be the last client middleware altering the
+// content of the request
+func HMAC(keyID string, key []byte) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		signature := hmacSignature(key, request, timestamp)
+
+		request.SetHeader(HeaderTimestamp, timestamp)
+		request.SetHeader(HeaderAuthorization, SchemeHMAC+" "+keyID+":"+base64.RawURLEncoding.EncodeToString(signature))
+		return next()
+	}
+}
+
+// HMACKeys is an Authenticator verifying requests signed by HMAC using one of the keys, by key id, which is the subject
+// of the principal. Requests with a timestamp further from the current time than window, defaulting to 5 minutes, are
+// rejected
+func HMACKeys(keys map[string][]byte, window time.Duration) Authenticator {
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+
+	return func(request *yarf.Msg) (Principal, error) {
+		credentials, ok := authorization(request, SchemeHMAC)
+		if !ok {
+			return Principal{}, ErrNoCredentials
+		}
+
+		keyID, encoded, ok := strings.Cut(credentials, ":")
+		if !ok {
+			return Principal{}, errors.New("malformed hmac credentials")
+		}
+		key, ok := keys[keyID]
+		if !ok {
+			return Principal{}, errors.New("unknown hmac key " + keyID)
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return Principal{}, errors.New("malformed hmac signature")
+		}
+
+		timestamp, _ := request.Headers[HeaderTimestamp].(string)
+		ms, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return Principal{}, errors.New("missing hmac timestamp")
+		}
+		if skew := time.Since(time.UnixMilli(ms)); skew > window || skew < -window {
+			return Principal{}, errors.New("hmac timestamp outside of window")
+		}
+
+		if !hmac.Equal(signature, hmacSignature(key, request, timestamp)) {
+			return Principal{}, errors.New("invalid hmac signature")
+		}
+		return Principal{Subject: keyID, Method: "hmac"}, nil
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/modfin/yarf"
+	"strings"
+	"time"
+)
+
+// JWTOptions defines how JWTs are issued by the JWT client middleware and verified by the JWTVerifier. Tokens are
+// signed with HS256 using the shared Key
+type JWTOptions struct {
+	Key []byte
+
+	// Subject is the sub claim of issued tokens, identifying the caller
+	Subject string
+
+	// Issuer and Audience are the iss and aud claims of issued tokens, and are required to match when verifying if set
+	Issuer   string
+	Audience string
+
+	// TTL is the lifetime of issued tokens, defaults to 1 minute
+	TTL time.Duration
+
+	// Leeway is the clock skew allowed when verifying exp and nbf, defaults to 30 seconds
+	Leeway time.Duration
+
+	// AllowNoExpiry accepts tokens without an exp claim when verifying, which are valid forever. Such tokens are
+	// rejected by default
+	AllowNoExpiry bool
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// SignJWT creates a HS256 signed JWT with the claims
+func SignJWT(key []byte, claims map[string]interface{}) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// JWT is a client middleware attaching a JWT, issued for every request according to the options, as a bearer token
+func JWT(options JWTOptions) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	if options.TTL <= 0 {
+		options.TTL = time.Minute
+	}
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		now := time.Now()
+		claims := map[string]interface{}{
+			"iat": now.Unix(),
+			"exp": now.Add(options.TTL).Unix(),
+		}
+		if options.Subject != "" {
+			claims["sub"] = options.Subject
+		}
+		if options.Issuer != "" {
+			claims["iss"] = options.Issuer
+		}
+		if options.Audience != "" {
+			claims["aud"] = options.Audience
+		}
+
+		token, err := SignJWT(options.Key, claims)
+		if err != nil {
+			return err
+		}
+		request.SetHeader(HeaderAuthorization, SchemeBearer+" "+token)
+		return next()
+	}
+}
+
+// JWTVerifier is an Authenticator verifying JWTs sent as bearer tokens, signed with HS256 using the key of the options.
+// The sub claim is the subject of the principal, and every claim is available in Principal.Claims. Tokens without a
+// numeric exp claim are rejected unless AllowNoExpiry is set. Tokens that are not JWTs are left for the next
+// authenticator
+func JWTVerifier(options JWTOptions) Authenticator {
+	if options.Leeway <= 0 {
+		options.Leeway = 30 * time.Second
+	}
+
+	return func(request *yarf.Msg) (Principal, error) {
+		token, ok := authorization(request, SchemeBearer)
+		if !ok || strings.Count(token, ".") != 2 {
+			return Principal{}, ErrNoCredentials
+		}
+
+		claims, err := verifyJWT(options.Key, token)
+		if err != nil {
+			return Principal{}, err
+		}
+
+		now := time.Now()
+		if exp, found := claims["exp"]; found || !options.AllowNoExpiry {
+			exp, ok := exp.(float64)
+			if !ok {
+				return Principal{}, errors.New("jwt has no valid exp claim")
+			}
+			if now.After(time.Unix(int64(exp), 0).Add(options.Leeway)) {
+				return Principal{}, errors.New("jwt has expired")
+			}
+		}
+		if nbf, found := claims["nbf"]; found {
+			nbf, ok := nbf.(float64)
+			if !ok {
+				return Principal{}, errors.New("jwt has no valid nbf claim")
+			}
+			if now.Before(time.Unix(int64(nbf), 0).Add(-options.Leeway)) {
+				return Principal{}, errors.New("jwt is not valid yet")
+			}
+		}
+		if options.Issuer != "" && claims["iss"] != options.Issuer {
+			return Principal{}, errors.New("jwt has invalid issuer")
+		}
+		if options.Audience != "" && !hasAudience(claims["aud"], options.Audience) {
+			return Principal{}, errors.New("jwt has invalid audience")
+		}
+
+		subject, _ := claims["sub"].(string)
+		return Principal{Subject: subject, Method: "jwt", Claims: claims}, nil
+	}
+}
+
+func verifyJWT(key []byte, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(data, &header) != nil {
+		return nil, errors.New("malformed jwt header")
+	}
+	if header.Alg != "HS256" {
+		return nil, errors.New("unsupported jwt algorithm " + header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed jwt signature")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid jwt signature")
+	}
+
+	claims := map[string]interface{}{}
+	data, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(data, &claims) != nil {
+		return nil, errors.New("malformed jwt claims")
+	}
+	return claims, nil
+}
+
+// hasAudience returns true if the aud claim, a string or a list of strings, contains audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
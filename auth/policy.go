@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"errors"
+	"github.com/modfin/yarf"
+)
+
+// Policy decides if the principal is allowed to perform the request to function. A returned RPCError is passed on to
+// the caller as is, and any other error results in yarf.StatusForbidden
+type Policy func(principal Principal, function string, request *yarf.Msg) error
+
+// Authorize is a server middleware applying the policy to every request, which must be authenticated by Authenticate
+// beforehand. It is added to the server for a common policy, or to a single function when handled for one of its own.
+func Authorize(policy Policy) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		principal, ok := PrincipalFrom(request.Context())
+		if !ok {
+			return yarf.NewRPCError(yarf.StatusUnauthorized, "unauthorized, "+ErrNoCredentials.Error())
+		}
+
+		function, _ := request.Function()
+		err := policy(principal, function, request)
+		if err != nil {
+			var rpcErr yarf.RPCError
+			if errors.As(err, &rpcErr) {
+				return rpcErr
+			}
+			return yarf.NewRPCError(yarf.StatusForbidden, "forbidden, "+err.Error())
+		}
+		return next()
+	}
+}
+
+// Subjects is a Policy allowing only the principals with one of the subjects
+func Subjects(subjects ...string) Policy {
+	allowed := map[string]bool{}
+	for _, s := range subjects {
+		allowed[s] = true
+	}
+	return func(principal Principal, function string, request *yarf.Msg) error {
+		if !allowed[principal.Subject] {
+			return errors.New(principal.Subject + " is not allowed to call " + function)
+		}
+		return nil
+	}
+}
+
+// Functions is a Policy applying the policy of the function requested, and denying functions without a policy
+func Functions(policies map[string]Policy) Policy {
+	return func(principal Principal, function string, request *yarf.Msg) error {
+		policy, ok := policies[function]
+		if !ok {
+			return errors.New("no policy allows calling " + function)
+		}
+		return policy(principal, function, request)
+	}
+}
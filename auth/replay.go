@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayCache records the requests received by VerifyRequests, in order to detect replays of them
+type ReplayCache interface {
+	// Seen returns true if key has been seen before, and records it until expires otherwise
+	Seen(key string, expires time.Time) bool
+}
+
+type memoryReplayCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	swept   time.Time
+	sweepIn time.Duration
+}
+
+// NewReplayCache creates a ReplayCache in memory, which detects replays sent to the same instance of a server only
+func NewReplayCache() ReplayCache {
+	return &memoryReplayCache{seen: map[string]time.Time{}, swept: time.Now(), sweepIn: time.Minute}
+}
+
+func (c *memoryReplayCache) Seen(key string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.swept) > c.sweepIn {
+		for k, e := range c.seen {
+			if now.After(e) {
+				delete(c.seen, k)
+			}
+		}
+		c.swept = now
+	}
+
+	if e, ok := c.seen[key]; ok && now.Before(e) {
+		return true
+	}
+	c.seen[key] = expires
+	return false
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/modfin/yarf"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature is the signature of a signed request, on the format "<algorithm> <key id>:<signature>"
+	HeaderSignature = "signature"
+
+	// HeaderSignedAt is the time a request was signed, in unix milliseconds
+	HeaderSignedAt = "signed-at"
+)
+
+const (
+	// AlgorithmHMAC signs using HMAC-SHA256 and a shared key
+	AlgorithmHMAC = "hmac-sha256"
+
+	// AlgorithmEd25519 signs using an Ed25519 private key, and verifies using its public key
+	AlgorithmEd25519 = "ed25519"
+)
+
+// SigningKey is the key the SignRequests client middleware signs requests with
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	sign      func(data []byte) []byte
+}
+
+// HMACSigningKey creates a SigningKey from a shared key, identified by id
+func HMACSigningKey(id string, key []byte) SigningKey {
+	return SigningKey{ID: id, Algorithm: AlgorithmHMAC, sign: func(data []byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return mac.Sum(nil)
+	}}
+}
+
+// Ed25519SigningKey creates a SigningKey from an Ed25519 private key, identified by id
+func Ed25519SigningKey(id string, key ed25519.PrivateKey) SigningKey {
+	return SigningKey{ID: id, Algorithm: AlgorithmEd25519, sign: func(data []byte) []byte {
+		return ed25519.Sign(key, data)
+	}}
+}
+
+// VerifyingKey is a key the VerifyRequests server middleware accepts signatures of
+type VerifyingKey struct {
+	ID        string
+	Algorithm string
+	verify    func(data []byte, signature []byte) bool
+}
+
+// HMACVerifyingKey creates a VerifyingKey from a shared key, identified by id
+func HMACVerifyingKey(id string, key []byte) VerifyingKey {
+	signer := HMACSigningKey(id, key)
+	return VerifyingKey{ID: id, Algorithm: AlgorithmHMAC, verify: func(data []byte, signature []byte) bool {
+		return hmac.Equal(signature, signer.sign(data))
+	}}
+}
+
+// Ed25519VerifyingKey creates a VerifyingKey from an Ed25519 public key, identified by id
+func Ed25519VerifyingKey(id string, key ed25519.PublicKey) VerifyingKey {
+	return VerifyingKey{ID: id, Algorithm: AlgorithmEd25519, verify: func(data []byte, signature []byte) bool {
+		return ed25519.Verify(key, data, signature)
+	}}
+}
+
+// signedData returns the data a message is signed over, which are the function, every header but the signature and the
+// timeout, and the content. The headers are encoded as json, which sorts them by name, making the data independent of
+// the protocol serializer and of the order of the headers. The json is decoded and encoded once more, into the form the
+// headers take once they have been sent using yarf.SerializerJson, so that numbers are signed as float64 on both sides,
+// e.g. integers above 2^53 are signed rounded. On the server, the function is the one the request is dispatched to,
+// binding the signature to it so that a captured request can not be sent to another function. The timeout is left out
+// since it is set by the client once the request is sent, after it is signed
+func signedData(msg *yarf.Msg) ([]byte, error) {
+	function, _ := msg.Function()
+
+	headers := make(map[string]interface{}, len(msg.Headers))
+	for k, v := range msg.Headers {
+		if k != HeaderSignature && k != yarf.HeaderTimeout {
+			headers[k] = v
+		}
+	}
+
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+	var canonical map[string]interface{}
+	err = json.Unmarshal(encoded, &canonical)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err = json.Marshal(canonical)
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte(function+"\n"), encoded...)
+	data = append(data, '\n')
+	return append(data, msg.Content...), nil
+}
+
+// SignRequests is a client middleware signing every request, its headers and content, using the key. Since any change
+// to the request after signing invalidates the signature, it shall be the last client middleware, which includes
+// middleware added to a single request using RPC.WithMiddleware
+func SignRequests(key SigningKey) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetHeader(HeaderSignedAt, strconv.FormatInt(time.Now().UnixMilli(), 10))
+
+		data, err := signedData(request)
+		if err != nil {
+			return err
+		}
+		signature := key.sign(data)
+
+		request.SetHeader(HeaderSignature, key.Algorithm+" "+key.ID+":"+base64.RawURLEncoding.EncodeToString(signature))
+		return next()
+	}
+}
+
+// VerifyOptions defines the keys and limits of the VerifyRequests middleware
+type VerifyOptions struct {
+	// Keys are the keys accepted, by id
+	Keys []VerifyingKey
+
+	// Window is the maximum difference between the time a request was signed and the time it is received, defaults to
+	// 5 minutes
+	Window time.Duration
+
+	// ReplayCache records the requests received within the window, defaults to a ReplayCache in memory. A cache shared
+	// by every instance of a server is required to detect replays sent to different instances
+	ReplayCache ReplayCache
+}
+
+// VerifyRequests is a server middleware verifying the signature of every request, which shall be the first server
+// middleware. Requests that are not signed by one of the keys, altered, or signed outside of the time window are
+// rejected with yarf.StatusInvalidSignature. Requests that has already been received are rejected with
+// yarf.StatusReplayed. The id of the key is the subject of the Principal put into the context of the request, unless
+// it already has one.
+//
+// Replays are detected by the request id and signing time, in milliseconds, of the request, which lets a request that
+// is retried, and thereby signed anew, through while a copy of the request is rejected.
+func VerifyRequests(options VerifyOptions) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	if options.Window <= 0 {
+		options.Window = 5 * time.Minute
+	}
+	if options.ReplayCache == nil {
+		options.ReplayCache = NewReplayCache()
+	}
+
+	keys := map[string]VerifyingKey{}
+	for _, k := range options.Keys {
+		keys[k.Algorithm+" "+k.ID] = k
+	}
+
+	invalid := func(reason string) error {
+		return yarf.NewRPCError(yarf.StatusInvalidSignature, "invalid signature, "+reason)
+	}
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		value, _ := request.Headers[HeaderSignature].(string)
+		if value == "" {
+			return invalid("request is not signed")
+		}
+
+		id, encoded, ok := strings.Cut(value, ":")
+		if !ok {
+			return invalid("malformed signature")
+		}
+		key, ok := keys[id]
+		if !ok {
+			return invalid("unknown key " + id)
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return invalid("malformed signature")
+		}
+
+		signedAt, _ := request.Headers[HeaderSignedAt].(string)
+		ms, err := strconv.ParseInt(signedAt, 10, 64)
+		if err != nil {
+			return invalid("missing signing time")
+		}
+		signed := time.UnixMilli(ms)
+		if skew := time.Since(signed); skew > options.Window || skew < -options.Window {
+			return invalid("signed outside of the time window")
+		}
+
+		data, err := signedData(request)
+		if err != nil {
+			return invalid(err.Error())
+		}
+		if !key.verify(data, signature) {
+			return invalid("signature does not match the request")
+		}
+
+		// The cache is only consulted for requests with a valid signature, so that it can not be filled by anyone else
+		requestID, _ := request.RequestID()
+		if options.ReplayCache.Seen(requestID+"@"+signedAt, signed.Add(options.Window)) {
+			return yarf.NewRPCError(yarf.StatusReplayed, "request "+requestID+" has already been received")
+		}
+
+		ctx := request.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if _, ok := PrincipalFrom(ctx); !ok {
+			request.WithContext(WithPrincipal(ctx, Principal{Subject: key.ID, Method: "signature"}))
+		}
+		return next()
+	}
+}
@@ -0,0 +1,150 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/auth"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+	"time"
+)
+
+func TestSignRequests(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, newClient := newAuthPair(t, auth.VerifyRequests(auth.VerifyOptions{
+		Keys: []auth.VerifyingKey{
+			auth.HMACVerifyingKey("billing", key),
+			auth.Ed25519VerifyingKey("reports", public),
+		},
+		Window: time.Minute,
+	}))
+
+	for _, serializer := range []yarf.Serializer{yarf.SerializerMsgPack(), yarf.SerializerJson()} {
+		client := newClient(auth.SignRequests(auth.HMACSigningKey("billing", key)))
+		client.WithProtocolSerializer(serializer)
+
+		// Integers above 2^53 do not survive the json protocol serializer as is
+		msg, err := client.Request("auth.whoami").WithParam("n", 42).WithParam("x", int64(1<<62+1)).WithParam("y", uint64(1<<63)).
+			WithIdempotent().WithTimeout(time.Second).Get()
+		if err != nil {
+			t.Fatalf("%s: %v", serializer.ContentType, err)
+		}
+		if msg.Param("subject").StringOr("") != "billing" || msg.Param("method").StringOr("") != "signature" {
+			t.Errorf("expected principal billing by signature, got %v", msg.Param("subject").StringOr(""))
+		}
+	}
+
+	subject, _ := whoami(t, newClient(auth.SignRequests(auth.Ed25519SigningKey("reports", private))))
+	if subject != "reports" {
+		t.Errorf("expected principal reports, got %s", subject)
+	}
+
+	err = newClient().Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusInvalidSignature)
+
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	err = newClient(auth.SignRequests(auth.Ed25519SigningKey("reports", other))).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusInvalidSignature)
+
+	tamper := func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetParam("n", 43)
+		return next()
+	}
+	err = newClient(auth.SignRequests(auth.HMACSigningKey("billing", key)), tamper).Request("auth.whoami").WithParam("n", 42).Done()
+	expectStatus(t, err, yarf.StatusInvalidSignature)
+}
+
+func TestSignatureWindow(t *testing.T) {
+	_, newClient := newAuthPair(t, auth.VerifyRequests(auth.VerifyOptions{
+		Keys:   []auth.VerifyingKey{auth.HMACVerifyingKey("billing", key)},
+		Window: 10 * time.Millisecond,
+	}))
+
+	delay := func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		time.Sleep(50 * time.Millisecond)
+		return next()
+	}
+	err := newClient(auth.SignRequests(auth.HMACSigningKey("billing", key)), delay).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusInvalidSignature)
+}
+
+func TestReplay(t *testing.T) {
+	_, newClient := newAuthPair(t, auth.VerifyRequests(auth.VerifyOptions{
+		Keys: []auth.VerifyingKey{auth.HMACVerifyingKey("billing", key)},
+	}))
+
+	// A replay is simulated by sending the signed request twice, as it was sent the first time
+	replay := func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		err := next()
+		if err != nil {
+			return err
+		}
+		response.Headers, response.Content = nil, nil
+		time.Sleep(2 * time.Millisecond)
+		return next()
+	}
+	err := newClient(replay, auth.SignRequests(auth.HMACSigningKey("billing", key))).Request("auth.whoami").Done()
+	if err != nil {
+		t.Errorf("expected a request signed anew not to be a replay, got %v", err)
+	}
+
+	err = newClient(auth.SignRequests(auth.HMACSigningKey("billing", key)), replay).Request("auth.whoami").Done()
+	expectStatus(t, err, yarf.StatusReplayed)
+}
+
+// capture is a transporter recording the data of the last request sent
+type capture struct {
+	yarf.Transporter
+	requestData []byte
+}
+
+func (c *capture) Call(ctx context.Context, function string, requestData []byte) ([]byte, error) {
+	c.requestData = requestData
+	return c.Transporter.Call(ctx, function, requestData)
+}
+
+func TestSignatureFunction(t *testing.T) {
+	transport, err := tmem.NewMemTransporter(tmem.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := yarf.NewServer(transport, "auth")
+	server.WithMiddleware(auth.VerifyRequests(auth.VerifyOptions{
+		Keys:   []auth.VerifyingKey{auth.HMACVerifyingKey("billing", key)},
+		Window: time.Minute,
+	}))
+	server.Handle("read", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+	server.Handle("delete", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+
+	wire := &capture{Transporter: transport}
+	client := yarf.NewClient(wire)
+	client.WithMiddleware(auth.SignRequests(auth.HMACSigningKey("billing", key)))
+
+	err = client.Request("auth.read").Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The captured request, sent to another function within the window
+	responseData, err := transport.Call(context.Background(), "auth.delete", wire.requestData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := yarf.UnmarshalStatus(responseData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != yarf.StatusInvalidSignature {
+		t.Errorf("expected status %d for a request sent to another function, got %d", yarf.StatusInvalidSignature, status)
+	}
+}
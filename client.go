@@ -30,6 +30,8 @@ type Client struct {
 	middleware         []Middleware
 	protocolSerializer Serializer
 	contentSerializer  Serializer
+	defaultTimeout     time.Duration
+	compression        *compression
 }
 
 // Close
@@ -45,6 +47,19 @@ func (c *Client) WithMiddleware(middleware ...Middleware) {
 	c.middleware = append(c.middleware, middleware...)
 }
 
+// WithDefaultTimeout sets the timeout of requests that are not given one using RPC.WithTimeout. For streams the
+// timeout limits the full duration of the stream
+func (c *Client) WithDefaultTimeout(timeout time.Duration) {
+	c.defaultTimeout = timeout
+}
+
+// WithCompression compresses the content of requests of at least threshold bytes using the compressor, where a
+// threshold of zero defaults to DefaultCompressionThreshold. It also lets servers know that the client accepts
+// compressed responses. Servers shall be upgraded to a version supporting compression before clients enable it
+func (c *Client) WithCompression(compressor Compressor, threshold int) {
+	c.compression = newCompression(compressor, threshold)
+}
+
 // WithProtocolSerializer sets the protocolSerializer used for transport.
 func (c *Client) WithProtocolSerializer(serializer Serializer) {
 	c.protocolSerializer = serializer
@@ -69,7 +84,7 @@ func (c *Client) Request(function string) *RPC {
 	return &RPC{
 		client:      c,
 		function:    function,
-		requestMsg:  &Msg{protocolSerializer: c.protocolSerializer, contentSerializer: c.contentSerializer},
+		requestMsg:  &Msg{protocolSerializer: c.protocolSerializer, contentSerializer: c.contentSerializer, compression: c.compression},
 		responseMsg: &Msg{}, // Automatically find deserializer
 		state:       builderState,
 		done:        make(chan bool),
@@ -87,6 +102,7 @@ type RPC struct {
 	client   *Client
 	function string
 	ctx      context.Context
+	timeout  time.Duration
 
 	mutex      sync.Mutex
 	state      int
@@ -151,14 +167,58 @@ func (r *RPC) WithContext(ctx context.Context) *RPC {
 	return r
 }
 
+// WithTimeout sets the timeout of the request, overriding the default timeout of the client. The timeout is applied
+// on top of the context of the request and is propagated to the server, it does nothing if called after exec(). It
+// takes precedence over timeouts of the transporter, see HasExplicitTimeout
+func (r *RPC) WithTimeout(timeout time.Duration) *RPC {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.state != builderState {
+		return r
+	}
+
+	r.timeout = timeout
+	return r
+}
+
 // WithUUID sets the uuid for the request enabling tracing of requests
+//
+// Deprecated: use WithRequestID
 func (r *RPC) WithUUID(uuid string) *RPC {
+	return r.WithRequestID(uuid)
+}
+
+// WithRequestID sets the id of the request enabling tracing of requests, instead of a generated one
+func (r *RPC) WithRequestID(id string) *RPC {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	if r.state != builderState {
 		return r
 	}
-	r.requestMsg.SetHeader(HeaderUUID, uuid)
+	r.requestMsg.SetRequestID(id)
+	return r
+}
+
+// WithParentID sets the id of the request that caused the request. It is otherwise taken from the request context,
+// see RequestIDFromContext
+func (r *RPC) WithParentID(id string) *RPC {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.state != builderState {
+		return r
+	}
+	r.requestMsg.SetParentID(id)
+	return r
+}
+
+// WithIdempotent marks the request as safe to perform more than once, allowing e.g. middleware.Retry to retry it
+func (r *RPC) WithIdempotent() *RPC {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.state != builderState {
+		return r
+	}
+	r.requestMsg.SetHeader(HeaderIdempotent, true)
 	return r
 }
 
@@ -227,21 +287,8 @@ func (r *RPC) exec() *RPCTransit {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if r.ctx == nil {
-		r.ctx = context.Background()
-	}
-
-	var cancel func()
-	r.ctx, cancel = context.WithCancel(r.ctx)
+	cancel := r.prepare()
 
-	r.requestMsg.ctx = r.ctx
-
-	r.requestMsg.SetHeader(HeaderFunction, r.function)
-
-	if suuid, ok := r.requestMsg.UUID(); suuid == "" || !ok {
-		v4 := uuid.New()
-		r.requestMsg.SetHeader(HeaderUUID, v4.String())
-	}
 	r.execOnce.Do(func() {
 		go func() {
 			r.mutex.Lock()
@@ -291,6 +338,44 @@ func (r *RPC) exec() *RPCTransit {
 	return &RPCTransit{r}
 }
 
+// prepare sets up the context and headers of the request before it is performed, and must be called holding the mutex
+func (r *RPC) prepare() (cancel func()) {
+	if r.ctx == nil {
+		r.ctx = context.Background()
+	}
+
+	timeout := r.timeout
+	if timeout == 0 {
+		timeout = r.client.defaultTimeout
+	}
+
+	if timeout > 0 {
+		r.ctx, cancel = context.WithTimeout(context.WithValue(r.ctx, explicitTimeoutKey, true), timeout)
+	} else {
+		r.ctx, cancel = context.WithCancel(r.ctx)
+	}
+
+	r.requestMsg.ctx = r.ctx
+
+	r.requestMsg.SetHeader(HeaderFunction, r.function)
+
+	if r.client.compression != nil {
+		r.requestMsg.SetHeader(HeaderAcceptEncoding, acceptedEncodings())
+	}
+
+	if id, ok := r.requestMsg.Headers[HeaderRequestID].(string); id == "" || !ok {
+		r.requestMsg.SetRequestID(uuid.New().String())
+	}
+
+	// Requests made while handling another request are caused by it
+	if _, ok := r.requestMsg.ParentID(); !ok {
+		if parent, ok := RequestIDFromContext(r.ctx); ok {
+			r.requestMsg.SetParentID(parent)
+		}
+	}
+	return cancel
+}
+
 func (r *RPC) doBind(request *Msg, response *Msg) error {
 	if s, ok := response.Status(); s >= 500 && ok {
 		err := RPCError{}
@@ -305,8 +390,26 @@ func (r *RPC) doBind(request *Msg, response *Msg) error {
 	return nil
 }
 
+// HasExplicitTimeout returns true if the deadline of ctx is given by a timeout set using RPC.WithTimeout or
+// Client.WithDefaultTimeout. Transporters having a timeout of their own shall let such a deadline take precedence, and
+// otherwise apply the earliest of their timeout and the deadline of ctx
+func HasExplicitTimeout(ctx context.Context) bool {
+	explicit, _ := ctx.Value(explicitTimeoutKey).(bool)
+	return explicit
+}
+
+// setTimeoutHeader sets the remaining budget of the request as the timeout header. It is called right before the request
+// is sent, rather than when it is prepared, so that a request retried by middleware is sent with the budget left
+func setTimeoutHeader(ctx context.Context, request *Msg) {
+	// The deadline is sent as the remaining budget, rounded up so that a request is never sent with a budget of zero
+	if deadline, ok := ctx.Deadline(); ok {
+		request.SetHeader(HeaderTimeout, (time.Until(deadline) + time.Millisecond - 1).Milliseconds())
+	}
+}
+
 func toClientRequestHandler(r *RPC) func(request *Msg, response *Msg) error {
 	return func(request *Msg, response *Msg) error {
+		setTimeoutHeader(r.ctx, request)
 
 		var reqBytes []byte
 		reqBytes, err := request.doMarshal()
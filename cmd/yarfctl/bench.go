@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func bench(cfg config, args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: yarfctl bench [-n requests] [-c concurrency] [-p key=value]... [-d json] <function>")
+		fs.PrintDefaults()
+	}
+	req := request{}
+	req.flags(fs)
+	n := fs.Int("n", 1000, "total number of requests")
+	c := fs.Int("c", 10, "number of concurrent requests")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 || *n < 1 || *c < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	function := fs.Arg(0)
+
+	client, close, err := cfg.client()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	// The content is read and decoded once and shared by every request, since stdin can only be read once
+	content, err := req.content()
+	if err != nil {
+		return err
+	}
+
+	var next, failed int64
+	latencies := make([]time.Duration, *n)
+	errs := map[string]int{}
+	var mu sync.Mutex
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < *c; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := atomic.AddInt64(&next, 1) - 1
+				if i >= int64(*n) {
+					return
+				}
+
+				rpc := req.build(&client, function, content)
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+				t := time.Now()
+				_, err := rpc.WithContext(ctx).Get()
+				latencies[i] = time.Since(t)
+				cancel()
+
+				if err != nil {
+					atomic.AddInt64(&failed, 1)
+					mu.Lock()
+					errs[err.Error()]++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+
+	fmt.Printf("Requests:     %d (%d failed)\n", *n, failed)
+	fmt.Printf("Concurrency:  %d\n", *c)
+	fmt.Printf("Duration:     %v\n", elapsed)
+	fmt.Printf("Throughput:   %.1f req/s\n", float64(*n)/elapsed.Seconds())
+	fmt.Printf("Latency p50:  %v\n", percentile(0.50))
+	fmt.Printf("Latency p90:  %v\n", percentile(0.90))
+	fmt.Printf("Latency p99:  %v\n", percentile(0.99))
+	fmt.Printf("Latency max:  %v\n", latencies[len(latencies)-1])
+
+	for e, count := range errs {
+		fmt.Printf("Error:        %d x %s\n", count, e)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/modfin/yarf"
+	"os"
+)
+
+func call(cfg config, args []string) error {
+	fs := flag.NewFlagSet("call", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: yarfctl call [-p key=value]... [-d json] [-q] <function>")
+		fs.PrintDefaults()
+	}
+	req := request{}
+	req.flags(fs)
+	quiet := fs.Bool("q", false, "only print the content of the response")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	client, close, err := cfg.client()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	content, err := req.content()
+	if err != nil {
+		return err
+	}
+	rpc := req.build(&client, fs.Arg(0), content)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+
+	msg, err := rpc.WithContext(ctx).Get()
+
+	var rpcErr yarf.RPCError
+	if err != nil && !errors.As(err, &rpcErr) {
+		return err
+	}
+
+	if *quiet {
+		err2 := printContent(os.Stdout, msg)
+		if err2 != nil {
+			return err2
+		}
+		return err
+	}
+
+	err2 := printMsg(os.Stdout, msg)
+	if err2 != nil {
+		return err2
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/modfin/yarf"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+func health(cfg config, args []string) error {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: yarfctl health <namespace>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	client, close, err := cfg.client()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	h, err := client.Health(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "STATUS\t%s\n", h.Status)
+	fmt.Fprintf(w, "VERSION\t%s\n", h.Version)
+	fmt.Fprintf(w, "UPTIME\t%s\n", h.Uptime.Round(time.Second))
+
+	var functions []string
+	for f := range h.Functions {
+		functions = append(functions, f)
+	}
+	sort.Strings(functions)
+	for _, f := range functions {
+		fmt.Fprintf(w, "  %s\t%s\n", f, h.Functions[f])
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if h.Status != yarf.HealthServing {
+		return errors.New("not serving")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func list(cfg config, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: yarfctl list <namespace>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	client, close, err := cfg.client()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	functions, err := client.Describe(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tREQUEST\tRESPONSE\tDESCRIPTION")
+	for _, f := range functions {
+		name := f.Name
+		if f.Stream {
+			name += " (stream)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, f.Request, f.Response, f.Description)
+		for _, p := range f.Params {
+			fmt.Fprintf(w, "  -p %s\t%s\t\t%s\n", p.Name, p.Type, p.Description)
+		}
+	}
+	return w.Flush()
+}
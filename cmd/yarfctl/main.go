@@ -0,0 +1,129 @@
+// Command yarfctl is a command line client for calling yarf functions over http or nats.
+//
+// Usage:
+//
+//	yarfctl [-url url] [-timeout duration] [-serializer msgpack|json] <command> [arguments]
+//
+// The commands are:
+//
+//	call <function>     calls a function and prints the status, headers and content of the response
+//	list <namespace>    lists the functions of the servers in the namespace, using the built-in describe function
+//	bench <function>    calls a function repeatedly and prints throughput and latencies
+//
+// The transport is picked from the scheme of the url, e.g. http://localhost:23456 or nats://localhost:4222. Params are
+// given using -p key=value, where value is parsed as json if possible and used as a string otherwise, and content is
+// given as json using -d, that is converted to the content serializer, e.g.
+//
+//	yarfctl -url nats://localhost:4222 call -p val1=3 -p val2=4 a.namespace.add
+//	yarfctl call -d '{"A": 5, "B": 7}' a.namespace.addTyped
+//	yarfctl bench -n 10000 -c 16 a.namespace.add
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/thttp"
+	"github.com/modfin/yarf/transport/tnats"
+	"net/url"
+	"os"
+	"time"
+)
+
+const usage = `Usage: yarfctl [flags] <command> [arguments]
+
+Commands:
+  call <function>     calls a function and prints the response
+  list <namespace>    lists the functions of the servers in the namespace
+  health <namespace>  prints the health of the servers in the namespace
+  bench <function>    calls a function repeatedly and prints throughput and latencies
+
+Run yarfctl <command> -h for the arguments of a command.
+
+Flags:
+`
+
+type config struct {
+	url        string
+	timeout    time.Duration
+	serializer string
+}
+
+func main() {
+	cfg := config{}
+	flag.StringVar(&cfg.url, "url", "http://localhost:"+thttp.StdPort, "url of the yarf servers, the scheme selects the transport, http, https or nats")
+	flag.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "timeout of a request")
+	flag.StringVar(&cfg.serializer, "serializer", "msgpack", "serializer used for protocol and content, msgpack or json")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var cmd func(cfg config, args []string) error
+	switch flag.Arg(0) {
+	case "call":
+		cmd = call
+	case "list":
+		cmd = list
+	case "health":
+		cmd = health
+	case "bench":
+		cmd = bench
+	default:
+		fmt.Fprintln(os.Stderr, "yarfctl: unknown command", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := cmd(cfg, flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yarfctl:", err)
+		os.Exit(1)
+	}
+}
+
+// client creates a yarf client using the transport given by the scheme of the url, close shall be called once done
+func (cfg config) client() (client yarf.Client, close func(), err error) {
+	u, err := url.Parse(cfg.url)
+	if err != nil {
+		return yarf.Client{}, nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		var transporter *thttp.HTTPTransporter
+		transporter, err = thttp.NewHTTPTransporter(thttp.Options{
+			Discovery: &thttp.DiscoveryDefault{Protocol: u.Scheme, Host: u.Hostname(), Port: u.Port()},
+		})
+		client = yarf.NewClient(transporter)
+	case "nats", "tls":
+		var transporter *tnats.NatsTransporter
+		transporter, err = tnats.NewNatsTransporter(cfg.url, cfg.timeout)
+		client = yarf.NewClient(transporter)
+	default:
+		return yarf.Client{}, nil, errors.New("unsupported url scheme " + u.Scheme)
+	}
+	if err != nil {
+		return yarf.Client{}, nil, err
+	}
+	close = func() { _ = client.Close() }
+
+	switch cfg.serializer {
+	case "msgpack":
+	case "json":
+		client.WithProtocolSerializer(yarf.SerializerJson())
+		client.WithSerializer(yarf.SerializerJson())
+	default:
+		close()
+		return yarf.Client{}, nil, errors.New("unsupported serializer " + cfg.serializer)
+	}
+
+	return client, close, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/modfin/yarf"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// params is a flag that can be repeated, collecting key=value pairs
+type params []yarf.Param
+
+func (p *params) String() string {
+	var s []string
+	for _, param := range *p {
+		s = append(s, fmt.Sprintf("%s=%v", param.Key(), param.Value()))
+	}
+	return strings.Join(s, ",")
+}
+
+func (p *params) Set(value string) error {
+	param, err := parseParam(value)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, param)
+	return nil
+}
+
+// parseParam parses a key=value pair, where value is decoded as json if possible, e.g. numbers, booleans and arrays,
+// and is used as a string otherwise
+func parseParam(s string) (yarf.Param, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return yarf.Param{}, errors.New("param must be on the form key=value, got " + s)
+	}
+
+	v, err := decodeJSON([]byte(value))
+	if err != nil {
+		return yarf.NewParam(key, value), nil
+	}
+	return yarf.NewParam(key, v), nil
+}
+
+// decodeJSON decodes json into generic values, keeping integers as int64 rather than float64 so that they can be bound
+// to integer fields once converted to msgpack
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after json value")
+	}
+	return fromNumbers(v), nil
+}
+
+func fromNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(t.String(), 10, 64); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = fromNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fromNumbers(e)
+		}
+	}
+	return v
+}
+
+// request holds the arguments shared by the commands performing requests
+type request struct {
+	params params
+	data   string
+}
+
+func (r *request) flags(fs *flag.FlagSet) {
+	fs.Var(&r.params, "p", "param on the form key=value, may be repeated")
+	fs.StringVar(&r.data, "d", "", "json content of the request, @file reads it from a file and - from stdin")
+}
+
+// content returns the content of the request decoded from json, or nil if no content was given
+func (r *request) content() (interface{}, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case r.data == "":
+		return nil, nil
+	case r.data == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(r.data, "@"):
+		data, err = os.ReadFile(r.data[1:])
+	default:
+		data = []byte(r.data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode content as json, %w", err)
+	}
+	return content, nil
+}
+
+// build creates the rpc for function with the params of the request and the content, as returned by content. The
+// content is read once and passed on, since it may be read from stdin
+func (r *request) build(client *yarf.Client, function string, content interface{}) *yarf.RPC {
+	rpc := client.Request(function).WithParams(r.params...)
+	if content != nil {
+		rpc = rpc.WithContent(content)
+	}
+	return rpc
+}
+
+// printMsg writes the status, headers and content of msg in a readable form
+func printMsg(w io.Writer, msg *yarf.Msg) error {
+	status, ok := msg.Status()
+	if !ok {
+		status = yarf.StatusOk
+	}
+	fmt.Fprintln(w, "Status:", status)
+
+	var keys []string
+	for k := range msg.Headers {
+		if k == yarf.HeaderStatus {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(w, "Headers:")
+	for _, k := range keys {
+		v, err := json.Marshal(msg.Headers[k])
+		if err != nil {
+			v = []byte(fmt.Sprint(msg.Headers[k]))
+		}
+		fmt.Fprintf(w, "  %s: %s\n", k, v)
+	}
+
+	fmt.Fprintln(w, "Content:")
+	return printContent(w, msg)
+}
+
+// printContent writes the content of msg as indented json, unless it is binary in which case it is written as is
+func printContent(w io.Writer, msg *yarf.Msg) error {
+	if len(msg.Content) == 0 {
+		return nil
+	}
+
+	contentType, _ := msg.ContentType()
+	if contentType == "binary/octet-stream" {
+		_, err := w.Write(msg.Content)
+		return err
+	}
+
+	var content interface{}
+	err := msg.BindContent(&content)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseParam(t *testing.T) {
+	table := []struct {
+		in       string
+		key      string
+		expected interface{}
+	}{
+		{"a=3", "a", int64(3)},
+		{"a=3.5", "a", 3.5},
+		{"a=true", "a", true},
+		{"a=hello", "a", "hello"},
+		{`a="3"`, "a", "3"},
+		{"a=[1,2]", "a", []interface{}{int64(1), int64(2)}},
+		{"a=b=c", "a", "b=c"},
+		{"a=", "a", ""},
+	}
+
+	for _, test := range table {
+		param, err := parseParam(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if param.Key() != test.key || !reflect.DeepEqual(param.Value(), test.expected) {
+			t.Errorf("expected %s to be parsed as %s=%#v, got %s=%#v", test.in, test.key, test.expected, param.Key(), param.Value())
+		}
+	}
+
+	_, err := parseParam("a")
+	if err == nil {
+		t.Error("expected an error for a param without value")
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	v, err := decodeJSON([]byte(`{"A": 5, "B": {"C": [1.5, 2]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"A": int64(5),
+		"B": map[string]interface{}{"C": []interface{}{1.5, int64(2)}},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %#v, got %#v", expected, v)
+	}
+
+	_, err = decodeJSON([]byte(`{"A": 5} {}`))
+	if err == nil {
+		t.Error("expected an error for trailing data")
+	}
+}
+
+func TestBuildReusesContent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "content.json")
+	err := os.WriteFile(file, []byte(`{"A": 5}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := request{data: "@" + file}
+	content, err := req.content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Requests built from the content shall not read the file again
+	err = os.Remove(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "yarfctl")
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		var content map[string]interface{}
+		err := request.BindContent(&content)
+		response.SetContent(content)
+		return err
+	})
+	client := yarf.NewClient(transport)
+
+	for i := 0; i < 3; i++ {
+		var res map[string]interface{}
+		err = req.build(&client, "yarfctl.echo", content).BindResponseContent(&res).Done()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(res["A"]) != "5" {
+			t.Errorf("expected content to be sent with request %d, got %#v", i, res)
+		}
+	}
+}
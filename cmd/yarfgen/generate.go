@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const yarfImport = "github.com/modfin/yarf"
+
+// annotation marks an interface for generation, it is followed by the namespace of the service
+const annotation = "//yarf:service"
+
+type service struct {
+	Name      string
+	Namespace string
+	Methods   []method
+}
+
+type method struct {
+	Name string
+	Req  string
+	Resp string
+}
+
+type file struct {
+	Package  string
+	Imports  []string
+	Services []service
+}
+
+// generate parses the go source and generates yarf servers and clients for the interfaces annotated with
+// "//yarf:service <namespace>", or for the interfaces named by types. If namespace is not empty it is used for
+// interfaces without an annotated namespace.
+func generate(filename string, src []byte, types []string, namespace string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := map[string]string{}
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name, line := path[strings.LastIndex(path, "/")+1:], spec.Path.Value
+		if spec.Name != nil {
+			name, line = spec.Name.Name, spec.Name.Name+" "+spec.Path.Value
+		}
+		imports[name] = line
+	}
+
+	out := file{Package: f.Name.Name}
+	used := map[string]bool{}
+
+	wanted := map[string]bool{}
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			iface, ok := typeSpec.Type.(*ast.InterfaceType)
+			if !ok {
+				continue
+			}
+
+			doc := typeSpec.Doc
+			if doc == nil {
+				doc = gen.Doc
+			}
+			ns, annotated := serviceNamespace(doc)
+
+			if len(wanted) > 0 && !wanted[typeSpec.Name.Name] {
+				continue
+			}
+			if len(wanted) == 0 && !annotated {
+				continue
+			}
+			delete(wanted, typeSpec.Name.Name)
+
+			if ns == "" {
+				ns = namespace
+			}
+			if ns == "" {
+				return nil, fmt.Errorf("%s: no namespace was provided for %s", fset.Position(typeSpec.Pos()), typeSpec.Name.Name)
+			}
+
+			s := service{Name: typeSpec.Name.Name, Namespace: ns}
+			for _, field := range iface.Methods.List {
+				fn, ok := field.Type.(*ast.FuncType)
+				if !ok || len(field.Names) == 0 {
+					return nil, fmt.Errorf("%s: embedded interfaces are not supported", fset.Position(field.Pos()))
+				}
+
+				m, err := toMethod(fset, field.Names[0].Name, fn, used)
+				if err != nil {
+					return nil, err
+				}
+				s.Methods = append(s.Methods, m)
+			}
+			out.Services = append(out.Services, s)
+		}
+	}
+
+	for t := range wanted {
+		return nil, fmt.Errorf("could not find interface %s", t)
+	}
+	if len(out.Services) == 0 {
+		return nil, errors.New("could not find any interface annotated with " + annotation)
+	}
+
+	for name := range used {
+		if name == "context" {
+			if imports[name] != "" && imports[name] != strconv.Quote("context") {
+				return nil, errors.New("the package context must not be imported under an other name")
+			}
+			continue
+		}
+		path, ok := imports[name]
+		if !ok {
+			return nil, fmt.Errorf("could not find import of %s", name)
+		}
+		if path == strconv.Quote(yarfImport) {
+			continue
+		}
+		out.Imports = append(out.Imports, path)
+	}
+	sort.Strings(out.Imports)
+
+	buf := bytes.Buffer{}
+	err = tmpl.Execute(&buf, out)
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+func serviceNamespace(doc *ast.CommentGroup) (namespace string, ok bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, c := range doc.List {
+		if c.Text == annotation || strings.HasPrefix(c.Text, annotation+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(c.Text, annotation)), true
+		}
+	}
+	return "", false
+}
+
+// toMethod validates that the method is on the form Name(ctx context.Context, req Req) (Resp, error)
+func toMethod(fset *token.FileSet, name string, fn *ast.FuncType, used map[string]bool) (method, error) {
+	pos := fset.Position(fn.Pos())
+	invalid := fmt.Errorf("%s: method %s must be on the form %s(context.Context, Req) (Resp, error)", pos, name, name)
+
+	params := flatten(fn.Params)
+	results := flatten(fn.Results)
+	if len(params) != 2 || len(results) != 2 {
+		return method{}, invalid
+	}
+
+	if render(fset, params[0]) != "context.Context" {
+		return method{}, invalid
+	}
+	if render(fset, results[1]) != "error" {
+		return method{}, invalid
+	}
+
+	for _, expr := range []ast.Expr{params[0], params[1], results[0]} {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					used[ident.Name] = true
+				}
+			}
+			return true
+		})
+	}
+
+	return method{
+		Name: name,
+		Req:  render(fset, params[1]),
+		Resp: render(fset, results[0]),
+	}, nil
+}
+
+// flatten returns the type of every parameter in the list, e.g. (a, b int) results in [int, int]
+func flatten(list *ast.FieldList) []ast.Expr {
+	var types []ast.Expr
+	if list == nil {
+		return types
+	}
+	for _, field := range list.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			types = append(types, field.Type)
+		}
+	}
+	return types
+}
+
+func render(fset *token.FileSet, expr ast.Expr) string {
+	buf := bytes.Buffer{}
+	_ = printer.Fprint(&buf, fset, expr)
+	return buf.String()
+}
+
+var tmpl = template.Must(template.New("yarfgen").Parse(`// Code generated by yarfgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"github.com/modfin/yarf"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{range $s := .Services}}
+// {{$s.Name}}Namespace is the yarf namespace that the functions of {{$s.Name}} are registered in
+const {{$s.Name}}Namespace = "{{$s.Namespace}}"
+
+// New{{$s.Name}}Server creates a yarf server in {{$s.Name}}Namespace with every method of impl registered as a function
+func New{{$s.Name}}Server(t yarf.Transporter, impl {{$s.Name}}, middleware ...yarf.Middleware) yarf.Server {
+	s := yarf.NewServer(t, {{$s.Name}}Namespace)
+	Register{{$s.Name}}(&s, impl, middleware...)
+	return s
+}
+
+// Register{{$s.Name}} registers every method of impl as a function of the server, which is expected to be created
+// with {{$s.Name}}Namespace as namespace
+func Register{{$s.Name}}(s *yarf.Server, impl {{$s.Name}}, middleware ...yarf.Middleware) {
+{{- range $s.Methods}}
+	yarf.HandleTyped(s, "{{.Name}}", impl.{{.Name}}, middleware...)
+{{- end}}
+}
+
+// {{$s.Name}}Client implements {{$s.Name}} by calling the functions in {{$s.Name}}Namespace using a yarf client
+type {{$s.Name}}Client struct {
+	client *yarf.Client
+}
+
+// New{{$s.Name}}Client creates a {{$s.Name}}Client using the yarf client
+func New{{$s.Name}}Client(client *yarf.Client) *{{$s.Name}}Client {
+	return &{{$s.Name}}Client{client: client}
+}
+
+var _ {{$s.Name}} = (*{{$s.Name}}Client)(nil)
+{{range $s.Methods}}
+// {{.Name}} calls the function {{$s.Namespace}}.{{.Name}}
+func (c *{{$s.Name}}Client) {{.Name}}(ctx context.Context, req {{.Req}}) ({{.Resp}}, error) {
+	resp, _, err := yarf.GetTyped[{{.Resp}}](c.client.Request({{$s.Name}}Namespace + ".{{.Name}}").
+		WithContext(ctx).
+		WithContent(req))
+	return resp, err
+}
+{{end}}{{end}}`))
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const source = `package calc
+
+import (
+	"context"
+	"time"
+	m "math/big"
+)
+
+type Req struct{ A, B int }
+
+//yarf:service a.calc
+type Calculator interface {
+	Add(ctx context.Context, req Req) (int, error)
+	Mul(context.Context, *m.Int) ([]time.Duration, error)
+}
+
+type Other interface {
+	Noop(ctx context.Context, req Req) (Req, error)
+}
+`
+
+func TestGenerate(t *testing.T) {
+	code, err := generate("calc.go", []byte(source), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"package calc",
+		`m "math/big"`,
+		`"time"`,
+		`const CalculatorNamespace = "a.calc"`,
+		`yarf.HandleTyped(s, "Add", impl.Add, middleware...)`,
+		"func (c *CalculatorClient) Mul(ctx context.Context, req *m.Int) ([]time.Duration, error)",
+		"yarf.GetTyped[[]time.Duration](c.client.Request(CalculatorNamespace + \".Mul\")",
+	} {
+		if !strings.Contains(string(code), expected) {
+			t.Errorf("expected generated code to contain %q, got\n%s", expected, code)
+		}
+	}
+
+	if strings.Contains(string(code), "Other") {
+		t.Error("expected interface without annotation not to be generated")
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	code, err := generate("calc.go", []byte(source), []string{"Other"}, "a.other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(code), `const OtherNamespace = "a.other"`) {
+		t.Errorf("expected namespace of Other to be a.other, got\n%s", code)
+	}
+	if strings.Contains(string(code), "Calculator") {
+		t.Error("expected only Other to be generated")
+	}
+
+	_, err = generate("calc.go", []byte(source), []string{"Other"}, "")
+	if err == nil {
+		t.Error("expected an error when no namespace is provided")
+	}
+
+	_, err = generate("calc.go", []byte(source), []string{"Missing"}, "a.missing")
+	if err == nil {
+		t.Error("expected an error for a missing interface")
+	}
+}
+
+func TestGenerateInvalidMethod(t *testing.T) {
+	for _, method := range []string{
+		"Add(req Req) (int, error)",
+		"Add(ctx context.Context, req Req) int",
+		"Add(ctx context.Context, req Req) (int, bool)",
+		"Add(ctx context.Context, a, b Req) (int, error)",
+	} {
+		src := "package calc\nimport \"context\"\ntype Req struct{}\n//yarf:service a.calc\ntype Calculator interface {\n" + method + "\n}\n"
+		_, err := generate("calc.go", []byte(src), nil, "")
+		if err == nil {
+			t.Errorf("expected an error for %s", method)
+		}
+	}
+}
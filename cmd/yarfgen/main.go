@@ -0,0 +1,66 @@
+// Command yarfgen generates yarf servers and typed clients from go interfaces.
+//
+// An interface is annotated for yarf by a "//yarf:service <namespace>" comment, and every method of it shall be on the
+// form Name(context.Context, Req) (Resp, error), e.g.
+//
+//	//yarf:service a.calculator
+//	type Calculator interface {
+//		Add(ctx context.Context, req AddReq) (AddResp, error)
+//	}
+//
+// For every interface yarfgen generates NewCalculatorServer and RegisterCalculator, that registers the methods of an
+// implementation as functions of a yarf server, and CalculatorClient, a typed client implementing the interface using
+// a yarf client. The code is generated in the same package as the interface, and yarfgen is preferably run using
+// go generate
+//
+//	//go:generate yarfgen -source calculator.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	source := flag.String("source", os.Getenv("GOFILE"), "go source file containing the interfaces, defaults to $GOFILE when run by go generate")
+	out := flag.String("out", "", "output file, defaults to <source>_yarf.go")
+	types := flag.String("type", "", "comma separated list of interfaces to generate for, defaults to all annotated with "+annotation)
+	namespace := flag.String("namespace", "", "namespace used for interfaces without an annotated namespace")
+	flag.Parse()
+
+	if *source == "" {
+		fmt.Fprintln(os.Stderr, "yarfgen: no source file was provided")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *out == "" {
+		*out = strings.TrimSuffix(*source, filepath.Ext(*source)) + "_yarf.go"
+	}
+
+	var names []string
+	if *types != "" {
+		names = strings.Split(*types, ",")
+	}
+
+	src, err := os.ReadFile(*source)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yarfgen:", err)
+		os.Exit(1)
+	}
+
+	code, err := generate(*source, src, names, *namespace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yarfgen:", err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(*out, code, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yarfgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,296 @@
+package yarf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCompressionThreshold is the size of content, in bytes, from which messages are compressed by default
+const DefaultCompressionThreshold = 1024
+
+// DefaultMaxDecompressedSize is the size, in bytes, that the content of a message may be decompressed to by default
+const DefaultMaxDecompressedSize = 32 << 20
+
+// ErrDecompressedTooLarge is returned when the content of a message decompresses to more than the size allowed
+var ErrDecompressedTooLarge = errors.New("decompressed content exceeds the maximum size allowed")
+
+var maxDecompressedSize atomic.Int64
+
+// SetMaxDecompressedSize sets the size, in bytes, that the content of a message received by a client or server may be
+// decompressed to. Messages exceeding it fail to unmarshal, protecting against small messages decompressing into huge
+// ones. It defaults to DefaultMaxDecompressedSize, which a size of zero restores
+func SetMaxDecompressedSize(size int) {
+	maxDecompressedSize.Store(int64(size))
+}
+
+// MaxDecompressedSize returns the size set by SetMaxDecompressedSize, or DefaultMaxDecompressedSize if none has been set
+func MaxDecompressedSize() int {
+	if size := maxDecompressedSize.Load(); size > 0 {
+		return int(size)
+	}
+	return DefaultMaxDecompressedSize
+}
+
+// Compressor compresses the content of messages, and is identified by its encoding in the content-encoding header.
+// Decompress shall fail with ErrDecompressedTooLarge rather than decompress data to more than limit bytes
+type Compressor struct {
+	Encoding   string
+	Compress   func(data []byte) ([]byte, error)
+	Decompress func(data []byte, limit int) ([]byte, error)
+}
+
+var compressors = struct {
+	sync.RWMutex
+	byEncoding map[string]Compressor
+	accepted   string
+}{byEncoding: map[string]Compressor{}}
+
+func init() {
+	RegisterCompressor(CompressorGzip())
+	RegisterCompressor(CompressorZstd())
+	RegisterCompressor(CompressorSnappy())
+}
+
+// RegisterCompressor lets a user register a compressor for a specific encoding, which makes yarf able to decompress
+// messages compressed using it. Gzip, zstd and snappy are registered by default
+func RegisterCompressor(compressor Compressor) {
+	compressors.Lock()
+	defer compressors.Unlock()
+	compressors.byEncoding[compressor.Encoding] = compressor
+
+	var encodings []string
+	for e := range compressors.byEncoding {
+		encodings = append(encodings, e)
+	}
+	sort.Strings(encodings)
+	compressors.accepted = strings.Join(encodings, ",")
+}
+
+func compressor(encoding string) (compressor Compressor, ok bool) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	compressor, ok = compressors.byEncoding[encoding]
+	return
+}
+
+// acceptedEncodings returns the encodings of every compressor registered, separated by comma
+func acceptedEncodings() string {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	return compressors.accepted
+}
+
+// CompressorGzip compresses using gzip
+func CompressorGzip() Compressor {
+	return Compressor{
+		Encoding: "gzip",
+		Compress: func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			_, err := w.Write(data)
+			if err != nil {
+				return nil, err
+			}
+			err = w.Close()
+			return buf.Bytes(), err
+		},
+		Decompress: func(data []byte, limit int) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+
+			content, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(content) > limit {
+				return nil, ErrDecompressedTooLarge
+			}
+			return content, nil
+		},
+	}
+}
+
+var zstdCodec struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	err     error
+
+	// decoders are created per limit, since the limit is an option of the decoder
+	mu       sync.Mutex
+	decoders map[int]*zstd.Decoder
+}
+
+func zstdInit() error {
+	zstdCodec.once.Do(func() {
+		zstdCodec.encoder, zstdCodec.err = zstd.NewWriter(nil)
+	})
+	return zstdCodec.err
+}
+
+func zstdDecoder(limit int) (*zstd.Decoder, error) {
+	zstdCodec.mu.Lock()
+	defer zstdCodec.mu.Unlock()
+
+	if decoder, ok := zstdCodec.decoders[limit]; ok {
+		return decoder, nil
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	if zstdCodec.decoders == nil {
+		zstdCodec.decoders = map[int]*zstd.Decoder{}
+	}
+	zstdCodec.decoders[limit] = decoder
+	return decoder, nil
+}
+
+// CompressorZstd compresses using zstd
+func CompressorZstd() Compressor {
+	return Compressor{
+		Encoding: "zstd",
+		Compress: func(data []byte) ([]byte, error) {
+			if err := zstdInit(); err != nil {
+				return nil, err
+			}
+			return zstdCodec.encoder.EncodeAll(data, nil), nil
+		},
+		Decompress: func(data []byte, limit int) ([]byte, error) {
+			decoder, err := zstdDecoder(limit)
+			if err != nil {
+				return nil, err
+			}
+			content, err := decoder.DecodeAll(data, nil)
+			if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+				return nil, ErrDecompressedTooLarge
+			}
+			return content, err
+		},
+	}
+}
+
+// CompressorSnappy compresses using snappy, which is fast but compresses less than gzip and zstd
+func CompressorSnappy() Compressor {
+	return Compressor{
+		Encoding: "snappy",
+		Compress: func(data []byte) ([]byte, error) {
+			return snappy.Encode(nil, data), nil
+		},
+		Decompress: func(data []byte, limit int) ([]byte, error) {
+			size, err := snappy.DecodedLen(data)
+			if err != nil {
+				return nil, err
+			}
+			if size > limit {
+				return nil, ErrDecompressedTooLarge
+			}
+			return snappy.Decode(nil, data)
+		},
+	}
+}
+
+// compression is the compression of messages configured on a client or server
+type compression struct {
+	compressor Compressor
+	threshold  int
+}
+
+func newCompression(compressor Compressor, threshold int) *compression {
+	if threshold <= 0 {
+		threshold = DefaultCompressionThreshold
+	}
+	return &compression{compressor: compressor, threshold: threshold}
+}
+
+// accepts returns true if the request accepts responses compressed using the encoding
+func accepts(request *Msg, encoding string) bool {
+	accepted, _ := request.Headers[HeaderAcceptEncoding].(string)
+	for _, e := range strings.Split(accepted, ",") {
+		if strings.TrimSpace(e) == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// compressed returns a copy of the message with its content compressed, if it is large enough and the compressed
+// content is smaller, or the message itself otherwise
+func (m *Msg) compressed() (*Msg, error) {
+	c := m.compression
+	if c == nil || len(m.Content) < c.threshold {
+		return m, nil
+	}
+	if _, ok := m.Headers[HeaderContentEncoding]; ok {
+		return m, nil
+	}
+
+	content, err := c.compressor.Compress(m.Content)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) >= len(m.Content) {
+		return m, nil
+	}
+
+	msg := *m
+	msg.Headers = make(map[string]interface{}, len(m.Headers)+1)
+	for k, v := range m.Headers {
+		msg.Headers[k] = v
+	}
+	msg.Headers[HeaderContentEncoding] = c.compressor.Encoding
+	msg.Content = content
+	return &msg, nil
+}
+
+// CompressContent compresses the content of the message right away, as it otherwise is once marshalled, if compression
+// is enabled for the message and its content is large enough. The content-encoding header is set if compressed, and the
+// message is not compressed again when marshalled. It is used by middleware turning the content into data that does not
+// compress, e.g. encrypt, which compresses the content before encrypting it
+func (m *Msg) CompressContent() error {
+	msg, err := m.compressed()
+	if err != nil {
+		return err
+	}
+	m.Headers, m.Content = msg.Headers, msg.Content
+	m.contentCompressed = true
+	return nil
+}
+
+// DecompressContent decompresses the content of the message if it has a content-encoding header, which it removes.
+// Messages are decompressed as they are unmarshalled, so it is only used by middleware restoring the header along with
+// content compressed using CompressContent
+func (m *Msg) DecompressContent() error {
+	return m.decompress()
+}
+
+// decompress decompresses the content of the message, if it is compressed, and removes the content-encoding header
+func (m *Msg) decompress() error {
+	encoding, ok := m.Headers[HeaderContentEncoding].(string)
+	if !ok {
+		return nil
+	}
+
+	c, ok := compressor(encoding)
+	if !ok {
+		return errors.New("could not find a compressor for content encoding " + encoding)
+	}
+
+	content, err := c.Decompress(m.Content, MaxDecompressedSize())
+	if err != nil {
+		return errors.New("could not decompress content encoded with " + encoding + ", " + err.Error())
+	}
+	m.Content = content
+	delete(m.Headers, HeaderContentEncoding)
+	return nil
+}
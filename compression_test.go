@@ -0,0 +1,176 @@
+package yarf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"strings"
+	"testing"
+)
+
+// wireTransporter records the headers of the last request and response as sent over the wire
+type wireTransporter struct {
+	yarf.Transporter
+	request  map[string]interface{}
+	response map[string]interface{}
+}
+
+func (w *wireTransporter) Call(ctx context.Context, function string, requestData []byte) ([]byte, error) {
+	responseData, err := w.Transporter.Call(ctx, function, requestData)
+	if err != nil {
+		return nil, err
+	}
+	w.request = wireHeaders(requestData)
+	w.response = wireHeaders(responseData)
+	return responseData, nil
+}
+
+func wireHeaders(data []byte) map[string]interface{} {
+	var msg yarf.Msg
+	_ = yarf.SerializerMsgPack().Unmarshal(data[bytes.IndexByte(data, '\n')+1:], &msg)
+	return msg.Headers
+}
+
+func newCompressionPair() (*wireTransporter, *yarf.Server, *yarf.Client) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	wire := &wireTransporter{Transporter: transport}
+
+	server := yarf.NewServer(transport, "compression")
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		var content string
+		err := request.BindContent(&content)
+		if err != nil {
+			return err
+		}
+		response.SetContent(content)
+		return nil
+	})
+	client := yarf.NewClient(wire)
+	return wire, &server, &client
+}
+
+func echo(client *yarf.Client, content string) (string, error) {
+	var res string
+	err := client.Request("compression.echo").WithContent(content).BindResponseContent(&res).Done()
+	return res, err
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("yarf ", 1000)
+
+	for _, compressor := range []yarf.Compressor{yarf.CompressorGzip(), yarf.CompressorZstd(), yarf.CompressorSnappy()} {
+		t.Run(compressor.Encoding, func(t *testing.T) {
+			wire, server, client := newCompressionPair()
+			server.WithCompression(compressor, 0)
+			client.WithCompression(compressor, 0)
+
+			res, err := echo(client, large)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != large {
+				t.Errorf("expected content to survive compression, got %d bytes", len(res))
+			}
+			if wire.request[yarf.HeaderContentEncoding] != compressor.Encoding {
+				t.Errorf("expected request to be encoded with %s, got %v", compressor.Encoding, wire.request[yarf.HeaderContentEncoding])
+			}
+			if wire.response[yarf.HeaderContentEncoding] != compressor.Encoding {
+				t.Errorf("expected response to be encoded with %s, got %v", compressor.Encoding, wire.response[yarf.HeaderContentEncoding])
+			}
+		})
+	}
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	wire, server, client := newCompressionPair()
+	server.WithCompression(yarf.CompressorGzip(), 2048)
+	client.WithCompression(yarf.CompressorGzip(), 2048)
+
+	small := strings.Repeat("a", 1000)
+	res, err := echo(client, small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != small {
+		t.Error("expected content to be echoed")
+	}
+	if _, ok := wire.request[yarf.HeaderContentEncoding]; ok {
+		t.Error("expected request below threshold not to be compressed")
+	}
+	if _, ok := wire.response[yarf.HeaderContentEncoding]; ok {
+		t.Error("expected response below threshold not to be compressed")
+	}
+
+	_, err = echo(client, strings.Repeat("a", 3000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wire.request[yarf.HeaderContentEncoding] != "gzip" {
+		t.Error("expected request above threshold to be compressed")
+	}
+}
+
+func TestCompressionNotAccepted(t *testing.T) {
+	wire, server, client := newCompressionPair()
+	server.WithCompression(yarf.CompressorZstd(), 0)
+
+	large := strings.Repeat("yarf ", 1000)
+	res, err := echo(client, large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != large {
+		t.Error("expected content to be echoed")
+	}
+	if _, ok := wire.response[yarf.HeaderContentEncoding]; ok {
+		t.Error("expected response not to be compressed for a client not accepting it")
+	}
+}
+
+func TestCompressionMaxDecompressedSize(t *testing.T) {
+	yarf.SetMaxDecompressedSize(4096)
+	defer yarf.SetMaxDecompressedSize(0)
+
+	for _, compressor := range []yarf.Compressor{yarf.CompressorGzip(), yarf.CompressorZstd(), yarf.CompressorSnappy()} {
+		t.Run(compressor.Encoding, func(t *testing.T) {
+			_, _, client := newCompressionPair()
+			client.WithCompression(compressor, 0)
+
+			content := strings.Repeat("a", 3000)
+			res, err := echo(client, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != content {
+				t.Error("expected content below the limit to be echoed")
+			}
+
+			// A small message decompressing to more than the limit
+			_, err = echo(client, strings.Repeat("a", 5000))
+			var rpcErr yarf.RPCError
+			if !errors.As(err, &rpcErr) || rpcErr.Status != yarf.StatusUnmarshalError {
+				t.Errorf("expected status %d for content above the limit, got %v", yarf.StatusUnmarshalError, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalStatusCompressed(t *testing.T) {
+	// The status is read without decompressing the content, which is not even valid here
+	msg := yarf.Msg{Headers: map[string]interface{}{yarf.HeaderStatus: yarf.StatusHandlerError, yarf.HeaderContentEncoding: "gzip"}, Content: []byte("not gzip")}
+	serializer := yarf.SerializerMsgPack()
+	data, err := serializer.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := yarf.UnmarshalStatus(append([]byte(serializer.ContentType+"\n"), data...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != yarf.StatusHandlerError {
+		t.Errorf("expected status %d, got %d", yarf.StatusHandlerError, status)
+	}
+}
@@ -0,0 +1,96 @@
+package yarf_test
+
+import (
+	"context"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+	"time"
+)
+
+func TestDeadlinePropagation(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "deadline")
+	client := yarf.NewClient(transport)
+
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error {
+		timeout, ok := request.Timeout()
+		if !ok || timeout <= 0 || timeout > time.Second {
+			t.Errorf("expected a timeout of at most a second, got %v", timeout)
+		}
+
+		deadline, ok := request.Context().Deadline()
+		if !ok || time.Until(deadline) > time.Second {
+			t.Errorf("expected deadline on the request context, got %v", deadline)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := client.Request("deadline.fn").WithContext(ctx).Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeadlineFromHeader(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "deadline")
+	client := yarf.NewClient(transport)
+
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error {
+		<-request.Context().Done()
+		return request.Context().Err()
+	})
+
+	// The client context has no deadline, the budget is only given by the header
+	start := time.Now()
+	err := client.Request("deadline.fn").
+		WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+			request.SetHeader(yarf.HeaderTimeout, 50)
+			return next()
+		}).
+		Done()
+
+	if err == nil || err.Error() != "510: context deadline exceeded" {
+		t.Errorf("expected handler to exceed the deadline, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the handler to be canceled after 50ms, took %v", time.Since(start))
+	}
+}
+
+func TestDeadlineRetried(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "deadline")
+	client := yarf.NewClient(transport)
+
+	var timeouts []time.Duration
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error {
+		timeout, _ := request.Timeout()
+		timeouts = append(timeouts, timeout)
+		return nil
+	})
+
+	// Sends the request twice with a backoff in between, like middleware.Retry
+	client.WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		if err := next(); err != nil {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+		return next()
+	})
+
+	err := client.Request("deadline.fn").WithTimeout(time.Second).Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeouts) != 2 {
+		t.Fatalf("expected the request to be sent twice, got %d", len(timeouts))
+	}
+	if timeouts[1] > timeouts[0]-150*time.Millisecond {
+		t.Errorf("expected the retried request to be sent with the budget left, got %v after %v", timeouts[1], timeouts[0])
+	}
+}
@@ -0,0 +1,187 @@
+package yarf
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// FunctionDescribe is the name of the built-in function, in the namespace of a server, that lists the functions
+// registered on the server
+const FunctionDescribe = "_describe"
+
+// Function describes a function registered on a server
+type Function struct {
+	// Name is the full name of the function, on the format "namespace.function"
+	Name        string
+	Description string
+
+	// Request and Response are the go types of the request and response content, if known
+	Request  string
+	Response string
+
+	Params []FunctionParam
+
+	// Stream is true if the function is registered using HandleStream, and shall be called using Client.Stream
+	Stream bool
+}
+
+// FunctionParam describes a param that a function reads from the request
+type FunctionParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+type registry struct {
+	mu        sync.Mutex
+	functions map[string]*registered
+
+	// server is the copy of the server that the built-in functions are executed by, i.e. with the middleware and
+	// serializers of, which is the copy last configured since NewServer returns the server by value
+	server *Server
+}
+
+type registered struct {
+	function Function
+	handled  bool
+}
+
+func (r *registry) get(name string) *registered {
+	if r.functions == nil {
+		r.functions = map[string]*registered{}
+	}
+	f, ok := r.functions[name]
+	if !ok {
+		f = &registered{function: Function{Name: name}}
+		r.functions[name] = f
+	}
+	return f
+}
+
+// register records a function as handled by the server
+func (s *Server) register(function string, stream bool) {
+	s.configured()
+
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	f := s.registry.get(function)
+	f.handled = true
+	f.function.Stream = stream
+}
+
+// configured records s as the copy of the server that the built-in functions are executed by, and makes sure that the
+// server answers describe and health requests, for servers not created by NewServer
+func (s *Server) configured() {
+	if s.registry == nil {
+		s.registry = &registry{}
+		s.listenBuiltins()
+	}
+
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	s.registry.server = s
+}
+
+// listenBuiltins listens to the built-in describe and health functions, which is done once the server is created so
+// that they are answered before any function is handled
+func (s *Server) listenBuiltins() {
+	s.registry.server = s
+
+	prefix := ""
+	if s.namespace != "" {
+		prefix = s.namespace + "."
+	}
+	s.listenBuiltin(prefix+FunctionDescribe, func(request *Msg, response *Msg) error {
+		response.SetContent(s.Functions())
+		return nil
+	})
+	s.listenBuiltin(prefix+FunctionHealth, func(request *Msg, response *Msg) error {
+		response.SetContent(s.Health())
+		return nil
+	})
+
+	if transporter, ok := s.transporter.(HealthTransporter); ok {
+		transporter.ListenHealth(s.namespace, s.Health)
+	}
+}
+
+func (s *Server) listenBuiltin(function string, handler func(request *Msg, response *Msg) error) {
+	r := s.registry
+	_ = s.transporter.Listen(function, func(ctx context.Context, requestData []byte) (responseData []byte) {
+		r.mu.Lock()
+		server := r.server
+		r.mu.Unlock()
+		return server.exec(ctx, function, requestData, nil, handler, nil)
+	})
+}
+
+// Describe adds metadata to a function of the server, which is listed by Functions and the built-in describe function.
+// Only the fields of description that are set are applied, the name of the function can not be changed.
+func (s *Server) Describe(function string, description Function) {
+	if s.namespace != "" {
+		function = s.namespace + "." + function
+	}
+	if s.registry == nil {
+		s.registry = &registry{}
+	}
+
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+
+	f := s.registry.get(function)
+	if description.Description != "" {
+		f.function.Description = description.Description
+	}
+	if description.Request != "" {
+		f.function.Request = description.Request
+	}
+	if description.Response != "" {
+		f.function.Response = description.Response
+	}
+	if description.Params != nil {
+		f.function.Params = append([]FunctionParam{}, description.Params...)
+	}
+}
+
+// Functions returns a description of every function registered on the server, sorted by name
+func (s *Server) Functions() []Function {
+	if s.registry == nil {
+		return nil
+	}
+
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+
+	var functions []Function
+	for _, f := range s.registry.functions {
+		if !f.handled {
+			continue
+		}
+		function := f.function
+		function.Params = append([]FunctionParam(nil), f.function.Params...)
+		functions = append(functions, function)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+	return functions
+}
+
+// Describe requests the description of every function registered on the server in namespace
+func (c *Client) Describe(namespace string) ([]Function, error) {
+	function := FunctionDescribe
+	if namespace != "" {
+		function = namespace + "." + function
+	}
+
+	var functions []Function
+	err := c.Request(function).BindResponseContent(&functions).Done()
+	return functions, err
+}
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
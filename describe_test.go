@@ -0,0 +1,48 @@
+package yarf_test
+
+import (
+	"context"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"reflect"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "describe")
+	client := yarf.NewClient(transport)
+
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+	server.Describe("echo", yarf.Function{
+		Description: "echoes the request",
+		Params:      []yarf.FunctionParam{{Name: "msg", Type: "string"}},
+	})
+	yarf.HandleTyped(&server, "add", func(ctx context.Context, req addReq) (addResp, error) {
+		return addResp{Sum: req.A + req.B}, nil
+	})
+	server.HandleStream("upload", func(stream *yarf.Stream) error {
+		return nil
+	})
+
+	expected := []yarf.Function{
+		{Name: "describe.add", Request: "yarf_test.addReq", Response: "yarf_test.addResp"},
+		{Name: "describe.echo", Description: "echoes the request", Params: []yarf.FunctionParam{{Name: "msg", Type: "string"}}},
+		{Name: "describe.upload", Stream: true},
+	}
+
+	functions := server.Functions()
+	if !reflect.DeepEqual(functions, expected) {
+		t.Fatalf("expected functions %+v, got %+v", expected, functions)
+	}
+
+	functions, err := client.Describe("describe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(functions, expected) {
+		t.Fatalf("expected described functions %+v, got %+v", expected, functions)
+	}
+}
@@ -0,0 +1,209 @@
+package yarf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Stream is a bidirectional stream of messages between a client and a handler. On the client side it is opened by
+// Client.Stream or RPC.Duplex and on the server side it is passed to handlers registered by Server.HandleStream.
+type Stream struct {
+	ctx       context.Context
+	cancel    func()
+	transport TransportStream
+	request   *Msg
+
+	protocolSerializer Serializer
+	contentSerializer  Serializer
+	compression        *compression
+
+	// client side only, rpc is the request the stream was opened by and ended is closed when the final message of
+	// the stream has been received
+	rpc   *RPC
+	final *Msg
+	ended chan struct{}
+	once  sync.Once
+}
+
+// Stream opens a bidirectional stream to function, see RPC.Duplex
+func (c *Client) Stream(function string) (*Stream, error) {
+	return c.Request(function).Duplex()
+}
+
+// Duplex performs the request by opening a bidirectional stream to the function, which shall be registered using
+// Server.HandleStream. The request message, with params and content, is sent as the first message of the stream and
+// is available to the handler through Stream.Request. Middleware is run for the lifetime of the stream, with the final
+// message sent by the server when the handler returns as response. It is only supported by transporters implementing
+// StreamTransporter.
+func (r *RPC) Duplex() (*Stream, error) {
+	transporter, ok := r.client.transporter.(StreamTransporter)
+	if !ok {
+		return nil, errors.New("transporter does not support streaming")
+	}
+
+	if !r.stateEq(builderState) {
+		return nil, errors.New("request has already been performed")
+	}
+	r.setState(transitState)
+
+	r.mutex.Lock()
+
+	s := &Stream{
+		request:            r.requestMsg,
+		protocolSerializer: r.client.protocolSerializer,
+		contentSerializer:  r.client.contentSerializer,
+		compression:        r.client.compression,
+		rpc:                r,
+		ended:              make(chan struct{}),
+	}
+	s.cancel = r.prepare()
+	s.ctx = r.ctx
+
+	opened := make(chan error, 1)
+
+	go func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		defer func() {
+			s.cancel()
+			r.setState(finishedState)
+			close(r.done)
+		}()
+
+		r.setState(requestState)
+
+		r.err = processMiddleware(r.requestMsg, r.responseMsg, func(request *Msg, response *Msg) error {
+			setTimeoutHeader(r.ctx, request)
+
+			reqBytes, err := request.doMarshal()
+			if err != nil {
+				return err
+			}
+
+			transport, err := transporter.CallDuplex(r.ctx, r.function)
+			if err != nil {
+				return err
+			}
+			defer transport.Close()
+
+			err = transport.Send(reqBytes)
+			if err != nil {
+				return err
+			}
+
+			s.transport = transport
+			opened <- nil
+
+			select {
+			case <-s.ended:
+				*response = *s.final
+				return nil
+			case <-r.ctx.Done():
+				return r.ctx.Err()
+			}
+		}, append(r.client.middleware, r.middleware...)...)
+
+		if r.err == nil {
+			r.err = r.doBind(r.requestMsg, r.responseMsg)
+		}
+
+		r.setState(responseState)
+
+		if s.transport == nil && r.err == nil {
+			r.err = errors.New("stream was never opened")
+		}
+		select {
+		case opened <- r.err:
+		default:
+		}
+	}()
+
+	r.mutex.Unlock()
+
+	err := <-opened
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Context returns the context of the stream, which is canceled when the stream is closed
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// Request returns the message that the stream was opened with
+func (s *Stream) Request() *Msg {
+	return s.request
+}
+
+// Send sends a message over the stream. If no protocolSerializer is set on the message, the one of the client or
+// server is used.
+func (s *Stream) Send(msg *Msg) error {
+	if msg.protocolSerializer.Marshal == nil {
+		msg.protocolSerializer = s.protocolSerializer
+	}
+	if msg.compression == nil {
+		msg.compression = s.compression
+	}
+
+	data, err := msg.doMarshal()
+	if err != nil {
+		return err
+	}
+	return s.transport.Send(data)
+}
+
+// Recv blocks until the next message of the stream is received. It returns io.EOF when the other side is done
+// sending, i.e. when the client has called CloseSend or when the handler has returned. If the handler failed, the
+// error it returned is received on the client side as an RPCError instead.
+func (s *Stream) Recv() (*Msg, error) {
+	data, err := s.transport.Recv()
+	if err != nil {
+		if s.rpc != nil && err == io.EOF {
+			err = errors.New("stream ended without a final message")
+		}
+		if s.rpc != nil {
+			s.cancel()
+		}
+		return nil, err
+	}
+
+	msg := &Msg{ctx: s.ctx, contentSerializer: s.contentSerializer}
+	err = msg.doUnmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if end, _ := msg.Headers[HeaderStreamEnd].(bool); end && s.rpc != nil {
+		s.once.Do(func() {
+			s.final = msg
+			close(s.ended)
+		})
+		<-s.rpc.done
+		if s.rpc.err != nil {
+			return nil, s.rpc.err
+		}
+		return nil, io.EOF
+	}
+
+	return msg, nil
+}
+
+// CloseSend closes the sending direction of the stream, letting the other side know no more messages will be sent
+func (s *Stream) CloseSend() error {
+	return s.transport.CloseSend()
+}
+
+// Close cancels the stream if it is still in flight and waits for it to be done. It shall be called by the client
+// when done with the stream.
+func (s *Stream) Close() error {
+	if s.rpc == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.rpc.done
+	return nil
+}
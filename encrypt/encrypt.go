@@ -0,0 +1,197 @@
+// Package encrypt provides end-to-end encryption of the content, and selected headers, of yarf messages using AEAD.
+// Since messages are encrypted before they are marshalled, it works on top of any Transporter and Serializer, keeping
+// messages confidential from brokers and proxies in between the client and the server.
+//
+// Content is compressed before it is encrypted, if compression is enabled on the client or server, since encrypted
+// content does not compress. Messages streamed using Msg.Send and Stream are not encrypted.
+package encrypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"github.com/modfin/yarf"
+)
+
+// HeaderKeyID is the id of the key a message is encrypted with, which marks the message as encrypted
+const HeaderKeyID = "encryption-key"
+
+// Options defines the keys and the headers encrypted by the Client and Server middleware
+type Options struct {
+	Keyring *Keyring
+
+	// Headers are the headers encrypted along with the content. Headers used by yarf to route and process messages,
+	// e.g. function, request-id and status, as well as headers read by other middleware, shall not be encrypted
+	Headers []string
+}
+
+// envelope is what is encrypted, the content and the headers selected, as well as the content-encoding header of
+// compressed content, serialized by msgpack
+type envelope struct {
+	Headers map[string]interface{}
+	Content []byte
+}
+
+var envelopeSerializer = yarf.SerializerMsgPack()
+
+// additionalData binds a ciphertext to the key, direction, function and request id of the message, so that it can not
+// be moved to another message undetected, e.g. a response replayed in place of the response to another request
+func additionalData(keyID string, direction string, function string, requestID string) []byte {
+	return []byte(keyID + "\n" + direction + "\n" + function + "\n" + requestID)
+}
+
+func seal(msg *yarf.Msg, keyID string, aead cipher.AEAD, headers []string, direction string, function string, requestID string) error {
+	err := msg.CompressContent()
+	if err != nil {
+		return err
+	}
+
+	env := envelope{Content: msg.Content}
+	for _, h := range append([]string{yarf.HeaderContentEncoding}, headers...) {
+		if v, ok := msg.Headers[h]; ok {
+			if env.Headers == nil {
+				env.Headers = map[string]interface{}{}
+			}
+			env.Headers[h] = v
+		}
+	}
+
+	plaintext, err := envelopeSerializer.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	for h := range env.Headers {
+		delete(msg.Headers, h)
+	}
+	msg.SetHeader(HeaderKeyID, keyID)
+	msg.Content = aead.Seal(nonce, nonce, plaintext, additionalData(keyID, direction, function, requestID))
+	return nil
+}
+
+func open(msg *yarf.Msg, keyring *Keyring, direction string, function string, requestID string) (keyID string, err error) {
+	keyID, _ = msg.Headers[HeaderKeyID].(string)
+	aead, ok := keyring.Key(keyID)
+	if !ok {
+		return keyID, errors.New("unknown encryption key " + keyID)
+	}
+
+	if len(msg.Content) < aead.NonceSize() {
+		return keyID, errors.New("encrypted content is too short")
+	}
+	nonce, ciphertext := msg.Content[:aead.NonceSize()], msg.Content[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(keyID, direction, function, requestID))
+	if err != nil {
+		return keyID, errors.New("could not decrypt message, " + err.Error())
+	}
+
+	env := envelope{}
+	err = envelopeSerializer.Unmarshal(plaintext, &env)
+	if err != nil {
+		return keyID, err
+	}
+
+	delete(msg.Headers, HeaderKeyID)
+	for h, v := range env.Headers {
+		msg.SetHeader(h, v)
+	}
+	msg.Content = env.Content
+	return keyID, msg.DecompressContent()
+}
+
+func encrypted(msg *yarf.Msg) bool {
+	_, ok := msg.Headers[HeaderKeyID]
+	return ok
+}
+
+// Client is a client middleware encrypting requests with the primary key of the keyring, and decrypting the responses.
+// Responses that are not encrypted are only passed on if they carry an error status, e.g. errors of the server or
+// transport before the request is decrypted, and are otherwise rejected with yarf.StatusDecryptError since they may be
+// forged. Changes to the request made by middleware after it, which includes middleware added to a single request
+// using RPC.WithMiddleware, are not encrypted
+func Client(options Options) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		function, _ := request.Function()
+		requestID, _ := request.RequestID()
+
+		keyID, aead, ok := options.Keyring.Primary()
+		if !ok {
+			return errors.New("the keyring has no primary key")
+		}
+
+		// The plaintext of the request is restored once sent, so that a request retried by middleware before this one
+		// is encrypted anew rather than encrypted twice
+		headers, content := make(map[string]interface{}, len(request.Headers)), request.Content
+		for k, v := range request.Headers {
+			headers[k] = v
+		}
+		defer func() {
+			request.Headers, request.Content = headers, content
+		}()
+
+		err := seal(request, keyID, aead, options.Headers, "request", function, requestID)
+		if err != nil {
+			return err
+		}
+
+		err = next()
+		if err != nil {
+			return err
+		}
+		if !encrypted(response) {
+			if status, ok := response.Status(); ok && status >= 500 {
+				return nil
+			}
+			return yarf.NewRPCError(yarf.StatusDecryptError, "response is not encrypted")
+		}
+
+		_, err = open(response, options.Keyring, "response", function, requestID)
+		if err != nil {
+			return yarf.NewRPCError(yarf.StatusDecryptError, err.Error())
+		}
+		return nil
+	}
+}
+
+// Server is a server middleware decrypting requests, and encrypting the responses with the key of the request.
+// Requests that are not encrypted, or encrypted with a key that is not in the keyring, are rejected with
+// yarf.StatusDecryptError. Errors of the handler are encrypted as well, and are returned to the client as usual.
+func Server(options Options) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		function, _ := request.Function()
+		requestID, _ := request.RequestID()
+
+		if !encrypted(request) {
+			return yarf.NewRPCError(yarf.StatusDecryptError, "request is not encrypted")
+		}
+		keyID, err := open(request, options.Keyring, "request", function, requestID)
+		if err != nil {
+			return yarf.NewRPCError(yarf.StatusDecryptError, err.Error())
+		}
+
+		err = next()
+
+		// The error is set as the content of the response here, rather than by the server, in order to encrypt it
+		if err != nil {
+			var rpcErr yarf.RPCError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = yarf.NewRPCError(yarf.StatusHandlerError, err.Error())
+			}
+			response.SetStatus(rpcErr.Status)
+			response.SetContent(rpcErr)
+		}
+
+		aead, ok := options.Keyring.Key(keyID)
+		if !ok {
+			return yarf.NewRPCError(yarf.StatusDecryptError, "the encryption key "+keyID+" has been removed")
+		}
+		return seal(response, keyID, aead, options.Headers, "response", function, requestID)
+	}
+}
@@ -0,0 +1,303 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/encrypt"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"strings"
+	"testing"
+	"time"
+)
+
+var keys = map[string][]byte{
+	"k1": bytes.Repeat([]byte{1}, 32),
+	"k2": bytes.Repeat([]byte{2}, 32),
+}
+
+func keyring(t *testing.T, ids ...string) *encrypt.Keyring {
+	k := encrypt.NewKeyring()
+	for _, id := range ids {
+		if err := k.Add(id, keys[id]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return k
+}
+
+// newEncryptPair returns a function creating clients encrypting with the keyring given, and the last message sent by
+// any of them, as it was sent after encryption
+func newEncryptPair(t *testing.T, serverKeys *encrypt.Keyring) (func(clientKeys *encrypt.Keyring) *yarf.Client, *yarf.Msg) {
+	transport := newEncryptServer(t, serverKeys)
+
+	wire := &yarf.Msg{}
+	return func(clientKeys *encrypt.Keyring) *yarf.Client {
+		client := yarf.NewClient(transport)
+		if clientKeys != nil {
+			client.WithMiddleware(encrypt.Client(encrypt.Options{Keyring: clientKeys, Headers: []string{"params"}}))
+		}
+		client.WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+			wire.Headers, wire.Content = map[string]interface{}{}, request.Content
+			for k, v := range request.Headers {
+				wire.Headers[k] = v
+			}
+			return next()
+		})
+		return &client
+	}, wire
+}
+
+// newEncryptServer returns the transport of a server decrypting requests using the keyring
+func newEncryptServer(t *testing.T, serverKeys *encrypt.Keyring) yarf.Transporter {
+	transport, err := tmem.NewMemTransporter(tmem.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := yarf.NewServer(transport, "encrypt")
+	server.WithMiddleware(encrypt.Server(encrypt.Options{Keyring: serverKeys, Headers: []string{"params"}}))
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		var content string
+		if err := request.BindContent(&content); err != nil {
+			return err
+		}
+		response.SetParam("n", request.Param("n").IntOr(0))
+		response.SetContent(content)
+		return nil
+	})
+	server.Handle("fail", func(request *yarf.Msg, response *yarf.Msg) error {
+		return yarf.NewRPCError(yarf.StatusHandlerError, "secret failure")
+	})
+	return transport
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var rpcErr yarf.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Status != status {
+		t.Errorf("expected status %d, got %v", status, err)
+	}
+}
+
+func echo(client *yarf.Client) (string, int64, error) {
+	var content string
+	msg, err := client.Request("encrypt.echo").WithParam("n", 42).WithContent("secret content").BindResponseContent(&content).Get()
+	if err != nil {
+		return "", 0, err
+	}
+	return content, msg.Param("n").IntOr(0), nil
+}
+
+func TestEncrypt(t *testing.T) {
+	newClient, wire := newEncryptPair(t, keyring(t, "k1"))
+
+	content, n, err := echo(newClient(keyring(t, "k1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "secret content" || n != 42 {
+		t.Errorf("expected the request echoed, got %q and %d", content, n)
+	}
+
+	if bytes.Contains(wire.Content, []byte("secret content")) {
+		t.Error("expected the content to be encrypted")
+	}
+	if _, ok := wire.Headers["params"]; ok {
+		t.Error("expected the params header to be encrypted")
+	}
+	if wire.Headers[encrypt.HeaderKeyID] != "k1" {
+		t.Errorf("expected the key id header, got %v", wire.Headers[encrypt.HeaderKeyID])
+	}
+
+	err = newClient(keyring(t, "k1")).Request("encrypt.fail").Done()
+	var rpcErr yarf.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Status != yarf.StatusHandlerError || rpcErr.Msg != "secret failure" {
+		t.Errorf("expected the error of the handler, got %v", err)
+	}
+
+	err = newClient(nil).Request("encrypt.echo").Done()
+	expectStatus(t, err, yarf.StatusDecryptError)
+
+	err = newClient(keyring(t, "k2")).Request("encrypt.echo").Done()
+	expectStatus(t, err, yarf.StatusDecryptError)
+}
+
+func TestKeyRotation(t *testing.T) {
+	serverKeys := keyring(t, "k1")
+	newClient, wire := newEncryptPair(t, serverKeys)
+
+	old := newClient(keyring(t, "k1"))
+
+	// The new key is added to the servers before it is used by any client
+	if err := serverKeys.Add("k2", keys["k2"]); err != nil {
+		t.Fatal(err)
+	}
+	rotatedKeys := keyring(t, "k1", "k2")
+	if err := rotatedKeys.SetPrimary("k2"); err != nil {
+		t.Fatal(err)
+	}
+	rotated := newClient(rotatedKeys)
+
+	for _, client := range []*yarf.Client{old, rotated} {
+		if _, _, err := echo(client); err != nil {
+			t.Error(err)
+		}
+	}
+	if wire.Headers[encrypt.HeaderKeyID] != "k2" {
+		t.Errorf("expected the primary key to be used, got %v", wire.Headers[encrypt.HeaderKeyID])
+	}
+
+	if err := rotatedKeys.Remove("k2"); err == nil {
+		t.Error("expected the primary key not to be removable")
+	}
+
+	if err := serverKeys.SetPrimary("k2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverKeys.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := echo(old)
+	expectStatus(t, err, yarf.StatusDecryptError)
+	if _, _, err := echo(rotated); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncryptRetry(t *testing.T) {
+	client := yarf.NewClient(newEncryptServer(t, keyring(t, "k1")))
+
+	var attempts int
+	client.WithMiddleware(
+		middleware.Retry(middleware.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, NonIdempotent: true}),
+		encrypt.Client(encrypt.Options{Keyring: keyring(t, "k1"), Headers: []string{"params"}}),
+		func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("unavailable")
+			}
+			return next()
+		},
+	)
+
+	content, n, err := echo(&client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the request to be retried once, got %d attempts", attempts)
+	}
+	if content != "secret content" || n != 42 {
+		t.Errorf("expected the retried request echoed, got %q and %d", content, n)
+	}
+}
+
+func TestEncryptPlaintextResponse(t *testing.T) {
+	client := yarf.NewClient(newEncryptServer(t, keyring(t, "k1")))
+
+	var status int
+	client.WithMiddleware(
+		encrypt.Client(encrypt.Options{Keyring: keyring(t, "k1")}),
+		func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+			err := next()
+			// A forged response, in place of the one of the server
+			response.Headers = map[string]interface{}{}
+			response.SetContent("forged")
+			if status != 0 {
+				response.SetStatus(status)
+			}
+			return err
+		},
+	)
+
+	_, _, err := echo(&client)
+	expectStatus(t, err, yarf.StatusDecryptError)
+
+	status = yarf.StatusHandlerError
+	_, _, err = echo(&client)
+	var rpcErr yarf.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Status == yarf.StatusDecryptError {
+		t.Errorf("expected a plaintext error response to be passed on, got %v", err)
+	}
+}
+
+func TestEncryptSwappedResponse(t *testing.T) {
+	client := yarf.NewClient(newEncryptServer(t, keyring(t, "k1")))
+
+	var captured *yarf.Msg
+	client.WithMiddleware(
+		encrypt.Client(encrypt.Options{Keyring: keyring(t, "k1")}),
+		func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+			err := next()
+			if captured == nil {
+				captured = &yarf.Msg{Headers: map[string]interface{}{}, Content: response.Content}
+				for k, v := range response.Headers {
+					captured.Headers[k] = v
+				}
+				return err
+			}
+			// The response to the first request, replayed in place of the response to this one
+			response.Headers, response.Content = captured.Headers, captured.Content
+			return err
+		},
+	)
+
+	if _, _, err := echo(&client); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := echo(&client)
+	expectStatus(t, err, yarf.StatusDecryptError)
+}
+
+func TestEncryptCompression(t *testing.T) {
+	transport, err := tmem.NewMemTransporter(tmem.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := yarf.NewServer(transport, "encrypt")
+	server.WithCompression(yarf.CompressorGzip(), 0)
+	server.WithMiddleware(encrypt.Server(encrypt.Options{Keyring: keyring(t, "k1")}))
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		var content string
+		if err := request.BindContent(&content); err != nil {
+			return err
+		}
+		response.SetContent(content)
+		return nil
+	})
+
+	client := yarf.NewClient(transport)
+	client.WithCompression(yarf.CompressorGzip(), 0)
+
+	// The messages as sent, after encryption
+	var request, response yarf.Msg
+	client.WithMiddleware(
+		encrypt.Client(encrypt.Options{Keyring: keyring(t, "k1")}),
+		func(req *yarf.Msg, resp *yarf.Msg, next yarf.NextMiddleware) error {
+			err := next()
+			request, response = *req, *resp
+			return err
+		},
+	)
+
+	large := strings.Repeat("secret content ", 1000)
+	var content string
+	err = client.Request("encrypt.echo").WithContent(large).BindResponseContent(&content).Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != large {
+		t.Errorf("expected the content echoed, got %d bytes", len(content))
+	}
+
+	for name, msg := range map[string]yarf.Msg{"request": request, "response": response} {
+		if len(msg.Content) >= len(large)/2 {
+			t.Errorf("expected the %s to be compressed before it is encrypted, got %d bytes", name, len(msg.Content))
+		}
+		if _, ok := msg.Headers[yarf.HeaderContentEncoding]; ok {
+			t.Errorf("expected the content-encoding header of the %s to be encrypted", name)
+		}
+	}
+}
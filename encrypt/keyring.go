@@ -0,0 +1,87 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"sync"
+)
+
+// Keyring holds the keys messages are encrypted and decrypted with, by key id. Messages are encrypted with the primary
+// key, and decrypted with the key they were encrypted with.
+//
+// Keys are rotated by adding the new key to the keyring of every client and server, then making it the primary key,
+// and finally removing the old key once no messages encrypted with it are in flight.
+type Keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]cipher.AEAD{}}
+}
+
+// Add adds a AES-GCM key, of 16, 24 or 32 bytes, to the keyring. The first key added is made the primary key
+func (k *Keyring) Add(id string, key []byte) error {
+	if id == "" {
+		return errors.New("a key must have an id")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = aead
+	if k.primary == "" {
+		k.primary = id
+	}
+	return nil
+}
+
+// SetPrimary makes the key with id the one new messages are encrypted with
+func (k *Keyring) SetPrimary(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return errors.New("no key " + id + " in the keyring")
+	}
+	k.primary = id
+	return nil
+}
+
+// Remove removes the key with id from the keyring, after which messages encrypted with it can not be decrypted. The
+// primary key can not be removed
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.primary {
+		return errors.New("the primary key " + id + " can not be removed")
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// Primary returns the id and AEAD of the primary key
+func (k *Keyring) Primary() (string, cipher.AEAD, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	aead, ok := k.keys[k.primary]
+	return k.primary, aead, ok
+}
+
+// Key returns the AEAD of the key with id
+func (k *Keyring) Key(id string) (cipher.AEAD, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	aead, ok := k.keys[id]
+	return aead, ok
+}
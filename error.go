@@ -19,3 +19,53 @@ func NewRPCError(status int, msg string) RPCError {
 func (e RPCError) Error() string {
 	return strconv.Itoa(e.Status) + ": " + e.Msg
 }
+
+// MarshalError encodes an RPCError as a response message using the default protocol serializer. It lets a
+// transporter respond to a request that never reached a handler, e.g. a function that is not listened to, with an
+// error that is returned to the client as is
+func MarshalError(err RPCError) []byte {
+	response := Msg{protocolSerializer: defaultSerializer(), contentSerializer: defaultSerializer()}
+	return toServerErrorFrom(err, &response)
+}
+
+// UnmarshalStatus decodes the status of a response message, which is StatusOk if it has none. An error is returned if
+// data is not a yarf message, letting a transporter tell responses apart from e.g. error pages of a proxy. Only the
+// headers are decoded, and the content is neither copied nor decompressed
+func UnmarshalStatus(data []byte) (status int, err error) {
+	ser, data, err := protocolSerializerOf(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var msg struct {
+		Headers map[string]interface{}
+	}
+	err = ser.Unmarshal(data, &msg)
+	if err != nil {
+		return 0, err
+	}
+
+	status64, ok := toInt(msg.Headers[HeaderStatus])
+	if !ok {
+		return StatusOk, nil
+	}
+	return int(status64), nil
+}
+
+// UnmarshalError decodes the RPCError of a response message, and returns false if data is not a yarf message
+// containing an error
+func UnmarshalError(data []byte) (err RPCError, ok bool) {
+	msg := Msg{}
+	if msg.doUnmarshal(data) != nil {
+		return err, false
+	}
+
+	status, ok := msg.Status()
+	if !ok || status < 500 {
+		return err, false
+	}
+
+	_ = msg.BindContent(&err)
+	err.Status = status
+	return err, true
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/modfin/yarf"
 	"github.com/modfin/yarf/middleware"
+	"io"
 	"log"
 	"time"
 )
@@ -318,6 +319,11 @@ func SwapWithSerializer(client yarf.Client, tuple Tuple) (res Tuple, err error)
 		Done()
 }
 
+// SwapTypedRequest swaps and multiplies the values of a tuple using a typed handler
+func SwapTypedRequest(client yarf.Client, tuple Tuple, multiplier int) (Tuple, error) {
+	return yarf.CallTyped[Tuple, Tuple](&client, "a.integration.swapTyped", tuple, yarf.NewParam("multiplier", multiplier))
+}
+
 // LenRequest returns the length of an array
 func LenRequest(client yarf.Client, len int) (*yarf.Msg, error) {
 	arr := make([]byte, len)
@@ -383,6 +389,103 @@ func ConcRequest(client yarf.Client, sleep int) error {
 
 
 
+// CountStreamRequest streams count messages from the server, each with binary content of size, and returns the index
+// of every message received along with the final response
+func CountStreamRequest(client yarf.Client, count int, size int, fail bool) ([]int64, *yarf.Msg, error) {
+	rpc := client.Request("a.integration.count").
+		WithParam("count", count).
+		WithParam("size", size).
+		WithParam("fail", fail)
+
+	stream := rpc.Stream()
+	defer stream.Close()
+
+	var res []int64
+	for stream.Next() {
+		msg := stream.Msg()
+		if len(msg.Content) != size {
+			return res, nil, fmt.Errorf("expected content of size %d, got %d", size, len(msg.Content))
+		}
+		res = append(res, msg.Param("i").IntOr(-1))
+	}
+
+	if stream.Err() != nil {
+		return res, nil, stream.Err()
+	}
+
+	msg, err := rpc.Get()
+	return res, msg, err
+}
+
+// EchoStreamRequest sends each value over a bidirectional stream and receives it doubled, one at the time
+func EchoStreamRequest(client yarf.Client, vals []int) ([]int64, error) {
+	stream, err := client.Stream("a.integration.echo")
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var res []int64
+	for _, val := range vals {
+		err = stream.Send(new(yarf.Msg).SetParam("val", val))
+		if err != nil {
+			return res, err
+		}
+
+		msg, err := stream.Recv()
+		if err != nil {
+			return res, err
+		}
+		res = append(res, msg.Param("res").IntOr(-1))
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return res, err
+	}
+
+	_, err = stream.Recv()
+	if err != io.EOF {
+		return res, fmt.Errorf("expected end of stream, got %v", err)
+	}
+	return res, nil
+}
+
+// UploadStreamRequest streams chunks of binary content of size to the server and returns the total size received by
+// it. The server fails the stream if it received less than min bytes.
+func UploadStreamRequest(client yarf.Client, chunks int, size int, min int) (int64, error) {
+	stream, err := client.Request("a.integration.upload").
+		WithParam("min", min).
+		Duplex()
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	for i := 0; i < chunks; i++ {
+		err = stream.Send(new(yarf.Msg).SetBinaryContent(make([]byte, size)))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return 0, err
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = stream.Recv()
+	if err != io.EOF {
+		return 0, fmt.Errorf("expected end of stream, got %v", err)
+	}
+	return msg.Param("res").IntOr(-1), nil
+}
+
 // RunClient uses provided transport interface to run some tests
 func RunClient(clientTransport yarf.Transporter) {
 
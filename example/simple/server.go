@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"github.com/modfin/yarf"
 	"github.com/modfin/yarf/middleware"
+	"io"
 	"reflect"
 	"time"
 )
@@ -249,6 +250,15 @@ func swapWithSerializer(req *yarf.Msg, resp *yarf.Msg) (err error) {
 	return
 }
 
+func swapTyped(ctx context.Context, t Tuple) (Tuple, error) {
+	print(" Got request swapTyped")
+
+	req, _ := yarf.RequestFromContext(ctx)
+	multiplier := int(req.Param("multiplier").IntOr(1))
+
+	return Tuple{Val1: t.Val2 * multiplier, Val2: t.Val1 * multiplier}, nil
+}
+
 func cat(req *yarf.Msg, resp *yarf.Msg) (err error) {
 
 	print(" Got request cat", reflect.TypeOf(req.Param("arr").Value()))
@@ -295,6 +305,9 @@ func StartServerWithSerializer(serverTransport yarf.Transporter, verbose bool, s
 	server.HandleFunc(swapWithSerializer)
 	server.HandleFunc(sha256)
 
+	print("Adding typed handler")
+	yarf.HandleTyped(&server, "swapTyped", swapTyped)
+
 	print("Adding rpc err handler")
 	server.Handle("rpc-err", rpcErr)
 
@@ -350,5 +363,76 @@ func StartServerWithSerializer(serverTransport yarf.Transporter, verbose bool, s
 		return nil
 	})
 
+	print("Adding count stream")
+	server.Handle("count", func(req *yarf.Msg, resp *yarf.Msg) (err error) {
+		print(" Got request count")
+
+		count := int(req.Param("count").IntOr(0))
+		size := int(req.Param("size").IntOr(0))
+
+		for i := 0; i < count; i++ {
+			msg := new(yarf.Msg).SetParam("i", i)
+			if size > 0 {
+				msg.SetBinaryContent(make([]byte, size))
+			}
+
+			err = resp.Send(msg)
+			if err != nil {
+				return err
+			}
+		}
+
+		if req.Param("fail").BoolOr(false) {
+			return yarf.NewRPCError(600, "stream failed")
+		}
+
+		resp.SetParam("res", count)
+
+		return nil
+	})
+
+	print("Adding echo duplex stream")
+	server.HandleStream("echo", func(stream *yarf.Stream) error {
+		print(" Got request echo")
+
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = stream.Send(new(yarf.Msg).SetParam("res", msg.Param("val").IntOr(0)*2))
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	print("Adding upload client stream")
+	server.HandleStream("upload", func(stream *yarf.Stream) error {
+		print(" Got request upload")
+
+		var total int
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			total += len(msg.Content)
+		}
+
+		if total < int(stream.Request().Param("min").IntOr(0)) {
+			return yarf.NewRPCError(600, "upload was too small")
+		}
+
+		return stream.Send(new(yarf.Msg).SetParam("res", total))
+	})
+
 	return server
 }
@@ -9,7 +9,6 @@ import (
 	"github.com/opentracing/basictracer-go/examples/dapperish"
 	"github.com/opentracing/opentracing-go"
 	"os"
-	"time"
 )
 
 func main() {
@@ -24,9 +23,11 @@ func main() {
 	}
 
 	simple.StartServerWithSerializer(serverTransport, true, yarf.SerializerMsgPack(), middleware.OpenTracing("Server> "))
-	go serverTransport.Start()
-
-	time.Sleep(200 * time.Millisecond)
+	err = serverTransport.StartAsync()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	clientTransport, err := thttp.NewHTTPTransporter(thttp.Options{Discovery: &thttp.DiscoveryDNSA{Host: "127.0.0.1"}})
 	if err != nil {
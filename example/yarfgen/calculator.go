@@ -0,0 +1,54 @@
+package yarfgen
+
+//go:generate go run github.com/modfin/yarf/cmd/yarfgen -source calculator.go
+
+import (
+	"context"
+	"errors"
+)
+
+// AddReq is the request of Calculator.Add
+type AddReq struct {
+	A int
+	B int
+}
+
+// AddResp is the response of Calculator.Add
+type AddResp struct {
+	Sum int
+}
+
+// DivReq is the request of Calculator.Div
+type DivReq struct {
+	Dividend float64
+	Divisor  float64
+}
+
+// DivResp is the response of Calculator.Div
+type DivResp struct {
+	Quotient float64
+}
+
+// Calculator is a service for doing simple math
+//
+//yarf:service a.calculator
+type Calculator interface {
+	Add(ctx context.Context, req AddReq) (AddResp, error)
+	Div(ctx context.Context, req DivReq) (DivResp, error)
+}
+
+// Calc implements Calculator
+type Calc struct{}
+
+// Add adds A and B
+func (Calc) Add(ctx context.Context, req AddReq) (AddResp, error) {
+	return AddResp{Sum: req.A + req.B}, nil
+}
+
+// Div divides Dividend by Divisor
+func (Calc) Div(ctx context.Context, req DivReq) (DivResp, error) {
+	if req.Divisor == 0 {
+		return DivResp{}, errors.New("division by zero")
+	}
+	return DivResp{Quotient: req.Dividend / req.Divisor}, nil
+}
@@ -0,0 +1,53 @@
+// Code generated by yarfgen. DO NOT EDIT.
+
+package yarfgen
+
+import (
+	"context"
+	"github.com/modfin/yarf"
+)
+
+// CalculatorNamespace is the yarf namespace that the functions of Calculator are registered in
+const CalculatorNamespace = "a.calculator"
+
+// NewCalculatorServer creates a yarf server in CalculatorNamespace with every method of impl registered as a function
+func NewCalculatorServer(t yarf.Transporter, impl Calculator, middleware ...yarf.Middleware) yarf.Server {
+	s := yarf.NewServer(t, CalculatorNamespace)
+	RegisterCalculator(&s, impl, middleware...)
+	return s
+}
+
+// RegisterCalculator registers every method of impl as a function of the server, which is expected to be created
+// with CalculatorNamespace as namespace
+func RegisterCalculator(s *yarf.Server, impl Calculator, middleware ...yarf.Middleware) {
+	yarf.HandleTyped(s, "Add", impl.Add, middleware...)
+	yarf.HandleTyped(s, "Div", impl.Div, middleware...)
+}
+
+// CalculatorClient implements Calculator by calling the functions in CalculatorNamespace using a yarf client
+type CalculatorClient struct {
+	client *yarf.Client
+}
+
+// NewCalculatorClient creates a CalculatorClient using the yarf client
+func NewCalculatorClient(client *yarf.Client) *CalculatorClient {
+	return &CalculatorClient{client: client}
+}
+
+var _ Calculator = (*CalculatorClient)(nil)
+
+// Add calls the function a.calculator.Add
+func (c *CalculatorClient) Add(ctx context.Context, req AddReq) (AddResp, error) {
+	resp, _, err := yarf.GetTyped[AddResp](c.client.Request(CalculatorNamespace + ".Add").
+		WithContext(ctx).
+		WithContent(req))
+	return resp, err
+}
+
+// Div calls the function a.calculator.Div
+func (c *CalculatorClient) Div(ctx context.Context, req DivReq) (DivResp, error) {
+	resp, _, err := yarf.GetTyped[DivResp](c.client.Request(CalculatorNamespace + ".Div").
+		WithContext(ctx).
+		WithContent(req))
+	return resp, err
+}
@@ -0,0 +1,125 @@
+package yarf
+
+import (
+	"sync"
+	"time"
+)
+
+// FunctionHealth is the name of the built-in function, in the namespace of a server, that reports the health of the
+// server and its functions
+const FunctionHealth = "_health"
+
+// HealthStatus is the serving status of a server or function
+type HealthStatus string
+
+const (
+	// HealthServing the server or function is ready to handle requests
+	HealthServing HealthStatus = "SERVING"
+
+	// HealthNotServing the server or function is not able to handle requests, e.g. while starting or shutting down
+	HealthNotServing HealthStatus = "NOT_SERVING"
+)
+
+// Health is the health of a server, as reported by the built-in health function
+type Health struct {
+	Namespace string
+	Status    HealthStatus
+	Version   string
+	Started   time.Time
+	Uptime    time.Duration
+
+	// Functions are the serving status of every function registered on the server, by full name
+	Functions map[string]HealthStatus
+}
+
+type health struct {
+	mu        sync.Mutex
+	started   time.Time
+	version   string
+	status    HealthStatus
+	functions map[string]HealthStatus
+}
+
+func newHealth() *health {
+	return &health{started: time.Now(), status: HealthServing}
+}
+
+func (s *Server) healthOf() *health {
+	if s.health == nil {
+		s.health = newHealth()
+	}
+	return s.health
+}
+
+// WithVersion sets the version of the server reported by the built-in health function
+func (s *Server) WithVersion(version string) {
+	h := s.healthOf()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.version = version
+}
+
+// SetServingStatus sets the serving status of the server, which is HealthServing by default. It is preferably set to
+// HealthNotServing while the server is not ready, e.g. while warming up or during a graceful shutdown
+func (s *Server) SetServingStatus(status HealthStatus) {
+	h := s.healthOf()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status = status
+}
+
+// SetFunctionStatus sets the serving status of a function of the server, which is HealthServing by default, e.g. when
+// a dependency of the function is unavailable
+func (s *Server) SetFunctionStatus(function string, status HealthStatus) {
+	if s.namespace != "" {
+		function = s.namespace + "." + function
+	}
+
+	h := s.healthOf()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.functions == nil {
+		h.functions = map[string]HealthStatus{}
+	}
+	h.functions[function] = status
+}
+
+// Health returns the health of the server and the functions registered on it
+func (s *Server) Health() Health {
+	h := s.healthOf()
+
+	functions := map[string]HealthStatus{}
+	for _, f := range s.Functions() {
+		functions[f.Name] = HealthServing
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for function, status := range h.functions {
+		if _, ok := functions[function]; ok {
+			functions[function] = status
+		}
+	}
+
+	return Health{
+		Namespace: s.namespace,
+		Status:    h.status,
+		Version:   h.version,
+		Started:   h.started,
+		Uptime:    time.Since(h.started),
+		Functions: functions,
+	}
+}
+
+// Health requests the health of the server in namespace
+func (c *Client) Health(namespace string) (Health, error) {
+	function := FunctionHealth
+	if namespace != "" {
+		function = namespace + "." + function
+	}
+
+	var health Health
+	err := c.Request(function).BindResponseContent(&health).Done()
+	return health, err
+}
@@ -0,0 +1,75 @@
+package yarf_test
+
+import (
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"reflect"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "health")
+	client := yarf.NewClient(transport)
+
+	server.WithVersion("1.2.3")
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+	server.Handle("db", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+	server.SetFunctionStatus("db", yarf.HealthNotServing)
+
+	health, err := client.Health("health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Namespace != "health" || health.Status != yarf.HealthServing || health.Version != "1.2.3" {
+		t.Errorf("unexpected health %+v", health)
+	}
+	if health.Uptime <= 0 || health.Started.IsZero() {
+		t.Errorf("expected uptime, got %v since %v", health.Uptime, health.Started)
+	}
+
+	expected := map[string]yarf.HealthStatus{
+		"health.echo": yarf.HealthServing,
+		"health.db":   yarf.HealthNotServing,
+	}
+	if !reflect.DeepEqual(health.Functions, expected) {
+		t.Errorf("expected functions %v, got %v", expected, health.Functions)
+	}
+
+	server.SetServingStatus(yarf.HealthNotServing)
+
+	health, err = client.Health("health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != yarf.HealthNotServing {
+		t.Errorf("expected server not to be serving, got %s", health.Status)
+	}
+}
+
+func TestHealthWithoutHandlers(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "empty")
+	client := yarf.NewClient(transport)
+
+	health, err := client.Health("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != yarf.HealthServing || len(health.Functions) != 0 {
+		t.Errorf("unexpected health %+v", health)
+	}
+
+	// Middleware added once the server is created applies to the built-in functions as well
+	server.WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		return yarf.NewRPCError(yarf.StatusUnauthorized, "unauthorized")
+	})
+	_, err = client.Health("empty")
+	if rpcErr, ok := err.(yarf.RPCError); !ok || rpcErr.Status != yarf.StatusUnauthorized {
+		t.Errorf("expected server middleware to apply to health, got %v", err)
+	}
+}
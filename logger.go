@@ -0,0 +1,23 @@
+package yarf
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger that yarf and its transports report internal events through, i.e. failures that can not be
+// returned as an error to the caller. It defaults to slog.Default(), and can be silenced by a logger with a handler
+// that discards every record.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logger returns the logger set by SetLogger, or slog.Default() if none has been set
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
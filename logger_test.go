@@ -0,0 +1,55 @@
+package yarf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	defer SetLogger(nil)
+
+	buf := bytes.Buffer{}
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	msg := Msg{}
+	msg.SetContentUsing("content", Serializer{
+		ContentType: "failing",
+		Marshal:     func(v interface{}) ([]byte, error) { return nil, errors.New("marshal failed") },
+	})
+
+	if msg.builderError == nil {
+		t.Error("expected builder error")
+	}
+	if !strings.Contains(buf.String(), `"level":"ERROR"`) || !strings.Contains(buf.String(), `"error":"marshal failed"`) {
+		t.Errorf("expected structured error to be logged, got %s", buf.String())
+	}
+}
+
+// requestTransporter is a transporter without support for streams
+type requestTransporter struct {
+	Transporter
+}
+
+func (requestTransporter) Listen(function string, toExec func(ctx context.Context, requestData []byte) (responseData []byte)) error {
+	return nil
+}
+
+func TestLoggerHandleStream(t *testing.T) {
+	defer SetLogger(nil)
+
+	buf := bytes.Buffer{}
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	server := NewServer(requestTransporter{}, "log")
+	server.HandleStream("chat", func(stream *Stream) error {
+		return nil
+	})
+
+	if !strings.Contains(buf.String(), `"level":"ERROR"`) || !strings.Contains(buf.String(), `"function":"log.chat"`) {
+		t.Errorf("expected the unsupported stream to be logged, got %s", buf.String())
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of the circuit breaker of a function
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through while counting failures
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests without performing them
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of requests through, probing if the function has recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerOptions defines when the circuit breaker of a function opens and closes
+type CircuitBreakerOptions struct {
+	// Threshold is the number of consecutive failures that opens the circuit, defaults to 5
+	Threshold int
+
+	// OpenTimeout is the time the circuit stays open before it is half-opened, defaults to 10s
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of concurrent requests let through while half-open, defaults to 1. The circuit is
+	// closed by a successful probe and opened again by a failed one
+	HalfOpenProbes int
+
+	// Statuses are the RPCError statuses that are counted as failures, in addition to errors from the transport layer
+	Statuses []int
+
+	// OnStateChange is called when the circuit of a function changes state
+	OnStateChange func(function string, from CircuitState, to CircuitState)
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+// CircuitBreaker is a client middleware that keeps track of failures per function. Once the threshold of consecutive
+// failures is reached, the circuit is opened and requests to the function fail fast with StatusCircuitOpen, rather than
+// waiting for a degraded service to time out. After OpenTimeout the circuit is half-opened, letting probes through.
+func CircuitBreaker(options CircuitBreakerOptions) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	if options.Threshold < 1 {
+		options.Threshold = 5
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = 10 * time.Second
+	}
+	if options.HalfOpenProbes < 1 {
+		options.HalfOpenProbes = 1
+	}
+
+	var mu sync.Mutex
+	circuits := map[string]*circuit{}
+
+	// transition must be called holding mu, the returned function notifies of the change and must be called without
+	transition := func(function string, c *circuit, to CircuitState) func() {
+		from := c.state
+		c.state = to
+		c.failures = 0
+		c.probes = 0
+		if to == CircuitOpen {
+			c.openedAt = time.Now()
+		}
+		if options.OnStateChange == nil || from == to {
+			return func() {}
+		}
+		return func() { options.OnStateChange(function, from, to) }
+	}
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		function, _ := request.Function()
+
+		mu.Lock()
+		c, ok := circuits[function]
+		if !ok {
+			c = &circuit{}
+			circuits[function] = c
+		}
+
+		notify := func() {}
+		if c.state == CircuitOpen && time.Since(c.openedAt) >= options.OpenTimeout {
+			notify = transition(function, c, CircuitHalfOpen)
+		}
+
+		state := c.state
+		if state == CircuitHalfOpen {
+			if c.probes >= options.HalfOpenProbes {
+				state = CircuitOpen
+			} else {
+				c.probes++
+			}
+		}
+		mu.Unlock()
+		notify()
+
+		if state == CircuitOpen {
+			return yarf.NewRPCError(yarf.StatusCircuitOpen, "circuit breaker is open for "+function)
+		}
+
+		err := next()
+
+		// Requests canceled by the caller say nothing about the health of the function
+		if ctx := request.Context(); ctx != nil && ctx.Err() == context.Canceled {
+			mu.Lock()
+			if state == CircuitHalfOpen && c.state == CircuitHalfOpen {
+				c.probes--
+			}
+			mu.Unlock()
+			return err
+		}
+
+		failed := options.failure(response, err)
+
+		mu.Lock()
+		switch {
+		case state == CircuitHalfOpen && c.state == CircuitHalfOpen && failed:
+			notify = transition(function, c, CircuitOpen)
+		case state == CircuitHalfOpen && c.state == CircuitHalfOpen:
+			notify = transition(function, c, CircuitClosed)
+		case c.state == CircuitClosed && failed:
+			c.failures++
+			if c.failures >= options.Threshold {
+				notify = transition(function, c, CircuitOpen)
+			}
+		case c.state == CircuitClosed:
+			c.failures = 0
+		}
+		mu.Unlock()
+		notify()
+
+		return err
+	}
+}
+
+// failure returns true if err, or the status of the response, counts as a failure of the function
+func (o CircuitBreakerOptions) failure(response *yarf.Msg, err error) bool {
+	status, ok := response.Status()
+
+	var rpcErr yarf.RPCError
+	if errors.As(err, &rpcErr) {
+		status, ok = rpcErr.Status, true
+	} else if err != nil {
+		return true
+	}
+
+	if !ok {
+		return false
+	}
+	for _, s := range o.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
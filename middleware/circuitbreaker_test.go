@@ -0,0 +1,98 @@
+package middleware_test
+
+import (
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var failing int32 = 1
+	var calls int64
+	transport, _ := tmem.NewMemTransporter(tmem.Options{
+		Failure: func(function string) error {
+			atomic.AddInt64(&calls, 1)
+			if atomic.LoadInt32(&failing) == 1 && function == "cb.fail" {
+				return errors.New("transport failure")
+			}
+			return nil
+		},
+	})
+	server := yarf.NewServer(transport, "cb")
+	server.Handle("fail", func(request *yarf.Msg, response *yarf.Msg) error { return nil })
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error { return nil })
+
+	var mu sync.Mutex
+	var changes []string
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(middleware.CircuitBreaker(middleware.CircuitBreakerOptions{
+		Threshold:   3,
+		OpenTimeout: 50 * time.Millisecond,
+		OnStateChange: func(function string, from middleware.CircuitState, to middleware.CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, function+" "+from.String()+" -> "+to.String())
+		},
+	}))
+
+	for i := 0; i < 3; i++ {
+		err := client.Request("cb.fail").Done()
+		if err == nil || err.Error() != "transport failure" {
+			t.Fatalf("expected transport failure, got %v", err)
+		}
+	}
+
+	err := client.Request("cb.fail").Done()
+	rpcErr, ok := err.(yarf.RPCError)
+	if !ok || rpcErr.Status != yarf.StatusCircuitOpen {
+		t.Fatalf("expected circuit to be open, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected the open circuit not to perform the request, got %d calls", calls)
+	}
+
+	err = client.Request("cb.ok").Done()
+	if err != nil {
+		t.Errorf("expected circuit of other functions to be closed, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	err = client.Request("cb.fail").Done()
+	if err == nil || err.Error() != "transport failure" {
+		t.Fatalf("expected failing probe, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&failing, 0)
+	err = client.Request("cb.fail").Done()
+	if err != nil {
+		t.Fatalf("expected successful probe, got %v", err)
+	}
+	err = client.Request("cb.fail").Done()
+	if err != nil {
+		t.Fatalf("expected circuit to be closed, got %v", err)
+	}
+
+	expected := []string{
+		"cb.fail closed -> open",
+		"cb.fail open -> half-open",
+		"cb.fail half-open -> open",
+		"cb.fail open -> half-open",
+		"cb.fail half-open -> closed",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != len(expected) {
+		t.Fatalf("expected state changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Fatalf("expected state changes %v, got %v", expected, changes)
+		}
+	}
+}
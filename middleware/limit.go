@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"github.com/modfin/yarf"
+	"math"
+	"sync"
+	"time"
+)
+
+// KeyFunc returns the key of a request that limits are applied per, e.g. the id of the caller
+type KeyFunc func(request *yarf.Msg) string
+
+// KeyParam uses the param of the request as key
+func KeyParam(name string) KeyFunc {
+	return func(request *yarf.Msg) string {
+		return request.Param(name).StringOr("")
+	}
+}
+
+// KeyHeader uses the header of the request as key
+func KeyHeader(name string) KeyFunc {
+	return func(request *yarf.Msg) string {
+		key, _ := request.Headers[name].(string)
+		return key
+	}
+}
+
+// limitKey returns the key the request is limited by, which always includes the function
+func limitKey(request *yarf.Msg, key KeyFunc) string {
+	function, _ := request.Function()
+	if key == nil {
+		return function
+	}
+	return function + "\x00" + key(request)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit is a server middleware that limits the rate of requests per function, and per key if one is provided,
+// using token buckets. A bucket holds up to burst tokens and is refilled with rate tokens per second. Requests made
+// when the bucket is empty are rejected with StatusTooManyRequests. A rate of zero or below defaults to 1, and a burst
+// below 1 defaults to 1.
+func RateLimit(rate float64, burst int, key KeyFunc) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	if rate <= 0 || math.IsNaN(rate) {
+		rate = 1
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	// A bucket that has been idle long enough to be full is the same as a new one, and is removed. The time is capped
+	// since it overflows a time.Duration for tiny rates
+	full := time.Duration(math.MaxInt64)
+	if seconds := float64(burst) / rate; seconds < float64(math.MaxInt64/time.Second) {
+		full = time.Duration(seconds * float64(time.Second))
+	}
+
+	var mu sync.Mutex
+	buckets := map[string]*bucket{}
+	lastSweep := time.Now()
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		k := limitKey(request, key)
+		now := time.Now()
+
+		mu.Lock()
+		if now.Sub(lastSweep) > full {
+			for k, b := range buckets {
+				if now.Sub(b.last) > full {
+					delete(buckets, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		b, ok := buckets[k]
+		if !ok {
+			b = &bucket{tokens: float64(burst), last: now}
+			buckets[k] = b
+		}
+
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		mu.Unlock()
+
+		if !allowed {
+			return yarf.NewRPCError(yarf.StatusTooManyRequests, "rate limit exceeded")
+		}
+		return next()
+	}
+}
+
+// ConcurrencyLimit is a server middleware that limits the number of requests in flight per function, and per key if
+// one is provided. Requests exceeding the limit are rejected with StatusTooManyRequests rather than queued. A max below
+// 1 defaults to 1.
+func ConcurrencyLimit(max int, key KeyFunc) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	if max < 1 {
+		max = 1
+	}
+
+	var mu sync.Mutex
+	inFlight := map[string]int{}
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		k := limitKey(request, key)
+
+		mu.Lock()
+		if inFlight[k] >= max {
+			mu.Unlock()
+			return yarf.NewRPCError(yarf.StatusTooManyRequests, "concurrency limit exceeded")
+		}
+		inFlight[k]++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			inFlight[k]--
+			if inFlight[k] == 0 {
+				delete(inFlight, k)
+			}
+			mu.Unlock()
+		}()
+
+		return next()
+	}
+}
@@ -0,0 +1,100 @@
+package middleware_test
+
+import (
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+	"time"
+)
+
+func isTooManyRequests(err error) bool {
+	rpcErr, ok := err.(yarf.RPCError)
+	return ok && rpcErr.Status == yarf.StatusTooManyRequests
+}
+
+func TestRateLimit(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "limit")
+	server.WithMiddleware(middleware.RateLimit(20, 2, middleware.KeyParam("caller")))
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error { return nil })
+	client := yarf.NewClient(transport)
+
+	call := func(caller string) error {
+		return client.Request("limit.fn").WithParam("caller", caller).Done()
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := call("a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := call("a"); !isTooManyRequests(err) {
+		t.Fatalf("expected rate limit to be exceeded, got %v", err)
+	}
+	if err := call("b"); err != nil {
+		t.Fatalf("expected other caller not to be limited, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := call("a"); err != nil {
+		t.Fatalf("expected bucket to be refilled, got %v", err)
+	}
+}
+
+func TestRateLimitTinyRate(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "limit")
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error { return nil }, middleware.RateLimit(1e-12, 1, nil))
+	client := yarf.NewClient(transport)
+
+	if err := client.Request("limit.fn").Done(); err != nil {
+		t.Fatal(err)
+	}
+	// The time for the bucket to be refilled overflows a time.Duration
+	if err := client.Request("limit.fn").Done(); !isTooManyRequests(err) {
+		t.Fatalf("expected rate limit to be exceeded, got %v", err)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "limit")
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error {
+		started <- struct{}{}
+		<-block
+		return nil
+	}, middleware.ConcurrencyLimit(1, nil))
+	client := yarf.NewClient(transport)
+
+	first := client.Request("limit.fn").Async()
+	<-started
+
+	if err := client.Request("limit.fn").Done(); !isTooManyRequests(err) {
+		t.Fatalf("expected concurrency limit to be exceeded, got %v", err)
+	}
+
+	close(block)
+	if _, err := first.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { <-started }()
+	if err := client.Request("limit.fn").Done(); err != nil {
+		t.Fatalf("expected request once the first is done, got %v", err)
+	}
+}
+
+func TestConcurrencyLimitDefault(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "limit")
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error { return nil }, middleware.ConcurrencyLimit(0, nil))
+	client := yarf.NewClient(transport)
+
+	if err := client.Request("limit.fn").Done(); err != nil {
+		t.Fatalf("expected an invalid limit to default to 1, got %v", err)
+	}
+}
@@ -1,36 +1,128 @@
 package middleware
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"github.com/modfin/yarf"
+	"log/slog"
+	"math/rand"
 	"time"
 )
 
-type loggingContent struct {
-	At       time.Time       `json:"at"`
-	Function string          `json:"function"`
-	Headers  json.RawMessage `json:"headers"`
+// LoggingOptions defines how requests are logged by the Logging middleware
+type LoggingOptions struct {
+	// Logger defaults to slog.Default()
+	Logger *slog.Logger
+
+	// Message is the message of every log record, defaults to "yarf request"
+	Message string
+
+	// Level is the level successful requests are logged at, defaults to info. Failed requests are logged at error
+	Level slog.Level
+
+	// Headers adds the headers of the request to the log record, where the values of the headers listed in Redact are
+	// replaced by "REDACTED". The authorization and signature headers, carrying the credentials of the auth package, are
+	// always redacted
+	Headers bool
+	Redact  []string
+
+	// SampleRate is the fraction of successful requests that are logged, failed requests are always logged. Defaults
+	// to 1, logging every request
+	SampleRate float64
 }
 
-// Logging is a middleware for logging requests on the server side.
-func Logging() func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+// Logging is a middleware for logging requests on both the server and the client side, using log/slog. A record is
+// logged once the request is done, with function, request id, parent id, duration, status, error and content sizes.
+func Logging(options ...LoggingOptions) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	o := LoggingOptions{}
+	if len(options) > 0 {
+		o = options[0]
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	if o.Message == "" {
+		o.Message = "yarf request"
+	}
+	if o.SampleRate <= 0 || o.SampleRate > 1 {
+		o.SampleRate = 1
+	}
 
-	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
-		l := loggingContent{}
-		l.Function, _ = request.Function()
-		l.At = time.Now()
-		l.Headers, _ = json.Marshal(request.Headers)
+	redact := map[string]bool{"authorization": true, "signature": true}
+	for _, h := range o.Redact {
+		redact[h] = true
+	}
 
-		b, _ := json.Marshal(l)
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		start := time.Now()
 
-		fmt.Println(string(b))
+		// The headers are read before the request is performed, since they may be altered along the way
+		var headers []any
+		if o.Headers {
+			for k, v := range request.Headers {
+				if redact[k] {
+					v = "REDACTED"
+				}
+				headers = append(headers, slog.Any(k, v))
+			}
+		}
 
 		err := next()
 
-		// TODO log errors...
+		status, ok := response.Status()
+		var rpcErr yarf.RPCError
+		if errors.As(err, &rpcErr) {
+			status, ok = rpcErr.Status, true
+		}
+		if !ok && err == nil {
+			status, ok = yarf.StatusOk, true
+		}
+
+		failed := err != nil || status >= 500
+		if !failed && o.SampleRate < 1 && rand.Float64() >= o.SampleRate {
+			return err
+		}
+
+		level := o.Level
+		if failed {
+			level = slog.LevelError
+		}
+
+		ctx := request.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if !o.Logger.Enabled(ctx, level) {
+			return err
+		}
+
+		function, _ := request.Function()
+		id, _ := request.RequestID()
+
+		attrs := []slog.Attr{
+			slog.String("function", function),
+			slog.String("request_id", id),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if parent, ok := request.ParentID(); ok {
+			attrs = append(attrs, slog.String("parent_id", parent))
+		}
+		if ok {
+			attrs = append(attrs, slog.Int("status", status))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		attrs = append(attrs,
+			slog.Int("request_size", len(request.Content)),
+			slog.Int("response_size", len(response.Content)),
+		)
+		if o.Headers {
+			attrs = append(attrs, slog.Group("headers", headers...))
+		}
+
+		o.Logger.LogAttrs(ctx, level, o.Message, attrs...)
 
 		return err
 	}
-
 }
@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "log")
+	server.WithMiddleware(middleware.Logging(middleware.LoggingOptions{
+		Logger:  logger,
+		Headers: true,
+		Redact:  []string{"token"},
+	}))
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error {
+		response.SetBinaryContent([]byte("pong"))
+		return nil
+	})
+	server.Handle("fail", func(request *yarf.Msg, response *yarf.Msg) error {
+		return yarf.NewRPCError(520, "failed")
+	})
+	client := yarf.NewClient(transport)
+
+	rpc := client.Request("log.ok").WithBinaryContent([]byte("ping"))
+	rpc.WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetHeader("token", "secret")
+		request.SetHeader("authorization", "Bearer secret")
+		request.SetHeader("signature", "hmac-sha256 billing:secret")
+		return next()
+	})
+	if err := rpc.Done(); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Request("log.fail").Done()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log records, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var ok, fail map[string]interface{}
+	_ = json.Unmarshal([]byte(lines[0]), &ok)
+	_ = json.Unmarshal([]byte(lines[1]), &fail)
+
+	if ok["level"] != "INFO" || ok["function"] != "log.ok" || ok["status"] != float64(200) ||
+		ok["request_size"] != float64(4) || ok["response_size"] != float64(4) {
+		t.Errorf("unexpected record of successful request %v", ok)
+	}
+	headers, _ := ok["headers"].(map[string]interface{})
+	for _, h := range []string{"token", "authorization", "signature"} {
+		if headers[h] != "REDACTED" {
+			t.Errorf("expected %s header to be redacted, got %v", h, headers)
+		}
+	}
+
+	if fail["level"] != "ERROR" || fail["status"] != float64(520) || fail["error"] != "520: failed" {
+		t.Errorf("unexpected record of failed request %v", fail)
+	}
+}
+
+func TestLoggingSampling(t *testing.T) {
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "log")
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error { return nil })
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(middleware.Logging(middleware.LoggingOptions{Logger: logger, SampleRate: 0.000001}))
+
+	for i := 0; i < 10; i++ {
+		_ = client.Request("log.ok").Done()
+	}
+	_ = client.Request("log.missing").Done()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"function":"log.missing"`) {
+		t.Errorf("expected only the failed request to be logged, got\n%s", buf.String())
+	}
+}
@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/modfin/yarf"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the request duration histogram buckets used by default
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MetricsOptions defines the naming of the metrics and the histogram buckets
+type MetricsOptions struct {
+	// Namespace and Subsystem prefixes the name of every metric, e.g. yarf_server_requests_total. Namespace defaults
+	// to yarf, and Subsystem is preferably set to server or client, making it possible to tell them apart
+	Namespace string
+	Subsystem string
+
+	// Buckets are the upper bounds of the request duration histogram in seconds, defaults to DefaultBuckets
+	Buckets []float64
+}
+
+type series struct {
+	function string
+	status   string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Metrics records request counts, latencies, requests in flight and content sizes, labeled by function and status,
+// and exposes them in the Prometheus text format. The same Metrics shall not be used for both a server and a client.
+type Metrics struct {
+	prefix  string
+	buckets []float64
+
+	mu            sync.Mutex
+	durations     map[series]*histogram
+	inFlight      map[string]int64
+	requestBytes  map[string]uint64
+	responseBytes map[string]uint64
+}
+
+// NewMetrics creates a Metrics, where Middleware is added to a server or client and the Metrics is mounted as an
+// http.Handler, e.g. on /metrics, for Prometheus to scrape
+func NewMetrics(options MetricsOptions) *Metrics {
+	if options.Namespace == "" {
+		options.Namespace = "yarf"
+	}
+	if options.Buckets == nil {
+		options.Buckets = DefaultBuckets
+	}
+
+	prefix := options.Namespace + "_"
+	if options.Subsystem != "" {
+		prefix += options.Subsystem + "_"
+	}
+
+	buckets := append([]float64(nil), options.Buckets...)
+	sort.Float64s(buckets)
+
+	return &Metrics{
+		prefix:        prefix,
+		buckets:       buckets,
+		durations:     map[series]*histogram{},
+		inFlight:      map[string]int64{},
+		requestBytes:  map[string]uint64{},
+		responseBytes: map[string]uint64{},
+	}
+}
+
+// Middleware records metrics of requests, on both servers and clients. On a server, the function label is the function
+// dispatched to, which the server sets as the function header of the request, keeping the number of series bounded by
+// the functions handled rather than by what clients send
+func (m *Metrics) Middleware(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	function, _ := request.Function()
+
+	m.mu.Lock()
+	m.inFlight[function]++
+	m.requestBytes[function] += uint64(len(request.Content))
+	m.mu.Unlock()
+
+	start := time.Now()
+	err := next()
+	elapsed := time.Since(start).Seconds()
+
+	s := series{function: function, status: metricsStatus(response, err)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[function]--
+	m.responseBytes[function] += uint64(len(response.Content))
+
+	h, ok := m.durations[s]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[s] = h
+	}
+	for i, le := range m.buckets {
+		if elapsed <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += elapsed
+	h.count++
+
+	return err
+}
+
+// metricsStatus returns the status label of a request, which is "error" for errors that are not an RPCError, i.e.
+// errors from the transport layer on a client and from the handler on a server
+func metricsStatus(response *yarf.Msg, err error) string {
+	var rpcErr yarf.RPCError
+	if errors.As(err, &rpcErr) {
+		return strconv.Itoa(rpcErr.Status)
+	}
+	if err != nil {
+		return "error"
+	}
+	if status, ok := response.Status(); ok {
+		return strconv.Itoa(status)
+	}
+	return strconv.Itoa(yarf.StatusOk)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(w)
+}
+
+// MetricsHandler returns a http.Handler writing the metrics of all of the provided Metrics, e.g. of both a server and
+// a client running in the same process
+func MetricsHandler(metrics ...*Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = writeMetrics(w, metrics)
+	})
+}
+
+// Write writes the metrics in the Prometheus text exposition format
+func (m *Metrics) Write(writer io.Writer) error {
+	return writeMetrics(writer, []*Metrics{m})
+}
+
+// family is a metric written by Metrics, where write writes the samples of a Metrics named name
+type family struct {
+	suffix string
+	help   string
+	kind   string
+	write  func(m *Metrics, w io.Writer, name string, all []series)
+}
+
+var families = []family{
+	{"requests_total", "Total number of requests.", "counter", func(m *Metrics, w io.Writer, name string, all []series) {
+		for _, s := range all {
+			fmt.Fprintf(w, "%s{function=%s,status=%s} %d\n", name, quote(s.function), quote(s.status), m.durations[s].count)
+		}
+	}},
+	{"request_duration_seconds", "Duration of requests in seconds.", "histogram", func(m *Metrics, w io.Writer, name string, all []series) {
+		for _, s := range all {
+			h := m.durations[s]
+			labels := "function=" + quote(s.function) + ",status=" + quote(s.status)
+			for i, le := range m.buckets {
+				fmt.Fprintf(w, "%s_bucket{%s,le=%s} %d\n", name, labels, quote(strconv.FormatFloat(le, 'g', -1, 64)), h.counts[i])
+			}
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+			fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+			fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+		}
+	}},
+	{"requests_in_flight", "Number of requests in flight.", "gauge", func(m *Metrics, w io.Writer, name string, all []series) {
+		for _, f := range sortedKeys(m.inFlight) {
+			fmt.Fprintf(w, "%s{function=%s} %d\n", name, quote(f), m.inFlight[f])
+		}
+	}},
+	{"request_bytes_total", "Total size of request content in bytes.", "counter", func(m *Metrics, w io.Writer, name string, all []series) {
+		for _, f := range sortedKeys(m.requestBytes) {
+			fmt.Fprintf(w, "%s{function=%s} %d\n", name, quote(f), m.requestBytes[f])
+		}
+	}},
+	{"response_bytes_total", "Total size of response content in bytes.", "counter", func(m *Metrics, w io.Writer, name string, all []series) {
+		for _, f := range sortedKeys(m.responseBytes) {
+			fmt.Fprintf(w, "%s{function=%s} %d\n", name, quote(f), m.responseBytes[f])
+		}
+	}},
+}
+
+// writeMetrics writes the metrics in the Prometheus text exposition format. Metrics sharing a prefix are written as the
+// same metric, with a single HELP and TYPE line each, which requires them to record different functions
+func writeMetrics(writer io.Writer, metrics []*Metrics) error {
+	w := bufio.NewWriter(writer)
+
+	// Metrics sharing a prefix are sorted next to each other, since the samples of a metric must not be split up
+	var unique []*Metrics
+	seen := map[*Metrics]bool{}
+	for _, m := range metrics {
+		if !seen[m] {
+			seen[m] = true
+			unique = append(unique, m)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool { return unique[i].prefix < unique[j].prefix })
+
+	all := make([][]series, len(unique))
+	for i, m := range unique {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		all[i] = m.sortedSeries()
+	}
+
+	for _, f := range families {
+		written := map[string]bool{}
+		for i, m := range unique {
+			name := m.prefix + f.suffix
+			if !written[name] {
+				written[name] = true
+				fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.kind)
+			}
+			f.write(m, w, name, all[i])
+		}
+	}
+
+	return w.Flush()
+}
+
+// sortedSeries returns the series recorded, sorted by function and status, and must be called while holding the lock
+func (m *Metrics) sortedSeries() []series {
+	var all []series
+	for s := range m.durations {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].function != all[j].function {
+			return all[i].function < all[j].function
+		}
+		return all[i].status < all[j].status
+	})
+	return all
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+
+	serverMetrics := middleware.NewMetrics(middleware.MetricsOptions{Subsystem: "server", Buckets: []float64{1, 0.5}})
+	server := yarf.NewServer(transport, "metrics")
+	server.WithMiddleware(serverMetrics.Middleware)
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error {
+		response.SetBinaryContent([]byte("pong"))
+		return nil
+	})
+	server.Handle("fail", func(request *yarf.Msg, response *yarf.Msg) error {
+		return yarf.NewRPCError(520, "failed")
+	})
+
+	clientMetrics := middleware.NewMetrics(middleware.MetricsOptions{Subsystem: "client", Buckets: []float64{1}})
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(clientMetrics.Middleware)
+
+	for i := 0; i < 2; i++ {
+		_ = client.Request("metrics.ok").WithBinaryContent([]byte("ping")).Done()
+	}
+	_ = client.Request("metrics.fail").Done()
+	_ = client.Request("metrics.missing").Done()
+
+	rec := httptest.NewRecorder()
+	middleware.MetricsHandler(serverMetrics, clientMetrics).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, expected := range []string{
+		"# TYPE yarf_server_requests_total counter\n",
+		`yarf_server_requests_total{function="metrics.ok",status="200"} 2`,
+		`yarf_server_requests_total{function="metrics.fail",status="520"} 1`,
+		`yarf_server_request_duration_seconds_bucket{function="metrics.ok",status="200",le="0.5"} 2`,
+		`yarf_server_request_duration_seconds_bucket{function="metrics.ok",status="200",le="1"} 2`,
+		`yarf_server_request_duration_seconds_bucket{function="metrics.ok",status="200",le="+Inf"} 2`,
+		`yarf_server_request_duration_seconds_count{function="metrics.ok",status="200"} 2`,
+		`yarf_server_requests_in_flight{function="metrics.ok"} 0`,
+		`yarf_server_request_bytes_total{function="metrics.ok"} 8`,
+		`yarf_server_response_bytes_total{function="metrics.ok"} 8`,
+		`yarf_client_requests_total{function="metrics.fail",status="520"} 1`,
+		`yarf_client_requests_total{function="metrics.missing",status="error"} 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected metrics to contain %s, got\n%s", expected, body)
+		}
+	}
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("expected prometheus content type, got %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestMetricsLabels(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+
+	a := middleware.NewMetrics(middleware.MetricsOptions{Subsystem: "server"})
+	b := middleware.NewMetrics(middleware.MetricsOptions{Subsystem: "server"})
+	server := yarf.NewServer(transport, "metrics")
+	server.Handle("a", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	}, a.Middleware)
+	server.Handle("b", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	}, b.Middleware)
+
+	// The function header sent by the client shall not become a label of its own
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		request.SetHeader(yarf.HeaderFunction, "made up")
+		return next()
+	})
+	_ = client.Request("metrics.a").Done()
+	_ = client.Request("metrics.b").Done()
+
+	rec := httptest.NewRecorder()
+	middleware.MetricsHandler(a, b).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, expected := range []string{
+		`yarf_server_requests_total{function="metrics.a",status="200"} 1`,
+		`yarf_server_requests_total{function="metrics.b",status="200"} 1`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected metrics to contain %s, got\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "made up") {
+		t.Errorf("expected the function header of the client not to be used as label, got\n%s", body)
+	}
+	if n := strings.Count(body, "# TYPE yarf_server_requests_total counter\n"); n != 1 {
+		t.Errorf("expected a single TYPE line of metrics sharing a prefix, got %d in\n%s", n, body)
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+)
+
+// instrumentationName is the name of the tracer used by the OpenTelemetry middleware
+const instrumentationName = "github.com/modfin/yarf/middleware"
+
+// OpenTelemetryOptions defines the tracer provider and propagator used by the OpenTelemetry middleware
+type OpenTelemetryOptions struct {
+	// TracerProvider defaults to the global one, otel.GetTracerProvider
+	TracerProvider trace.TracerProvider
+
+	// Propagator defaults to W3C trace context, propagating the traceparent and tracestate headers
+	Propagator propagation.TextMapPropagator
+}
+
+func (o OpenTelemetryOptions) init() (trace.Tracer, propagation.TextMapPropagator) {
+	if o.TracerProvider == nil {
+		o.TracerProvider = otel.GetTracerProvider()
+	}
+	if o.Propagator == nil {
+		o.Propagator = propagation.TraceContext{}
+	}
+	return o.TracerProvider.Tracer(instrumentationName), o.Propagator
+}
+
+// headerCarrier lets the propagator read and write the headers of a message
+type headerCarrier struct {
+	msg *yarf.Msg
+}
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c.msg.Headers[key].(string)
+	return value
+}
+
+func (c headerCarrier) Set(key string, value string) {
+	c.msg.SetHeader(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Headers))
+	for k := range c.msg.Headers {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// OpenTelemetryClient is a client middleware that starts a span for every request, as a child of any span in the
+// request context, and propagates it to the server through the headers of the request
+func OpenTelemetryClient(options OpenTelemetryOptions) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	tracer, propagator := options.init()
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		function, _ := request.Function()
+
+		ctx := request.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		ctx, span := tracer.Start(ctx, function, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.String("rpc.system", "yarf"),
+			attribute.String("rpc.method", function),
+			attribute.Int("rpc.yarf.request_size", len(request.Content)),
+		))
+		defer span.End()
+
+		propagator.Inject(ctx, headerCarrier{request})
+		request.WithContext(ctx)
+
+		err := next()
+
+		endSpan(span, response, err)
+		return err
+	}
+}
+
+// OpenTelemetryServer is a server middleware that starts a span for every request, as a child of the span propagated
+// by the client, if any. The span is available to the handler through the context of the request, Msg.Context
+func OpenTelemetryServer(options OpenTelemetryOptions) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	tracer, propagator := options.init()
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		function, _ := request.Function()
+
+		ctx := request.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = propagator.Extract(ctx, headerCarrier{request})
+
+		ctx, span := tracer.Start(ctx, function, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("rpc.system", "yarf"),
+			attribute.String("rpc.method", function),
+			attribute.Int("rpc.yarf.request_size", len(request.Content)),
+		))
+		defer span.End()
+
+		request.WithContext(ctx)
+
+		err := next()
+
+		endSpan(span, response, err)
+		return err
+	}
+}
+
+func endSpan(span trace.Span, response *yarf.Msg, err error) {
+	span.SetAttributes(attribute.Int("rpc.yarf.response_size", len(response.Content)))
+
+	var rpcErr yarf.RPCError
+	status, ok := response.Status()
+	if errors.As(err, &rpcErr) {
+		status, ok = rpcErr.Status, true
+	}
+	if ok {
+		span.SetAttributes(attribute.Int("rpc.yarf.status", status))
+	}
+
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case ok && status >= 500:
+		span.SetStatus(codes.Error, "status "+strconv.Itoa(status))
+	}
+}
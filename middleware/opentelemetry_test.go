@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestOpenTelemetry(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	options := middleware.OpenTelemetryOptions{TracerProvider: provider}
+
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "otel")
+	server.WithMiddleware(middleware.OpenTelemetryServer(options))
+
+	var handlerSpan trace.SpanContext
+	server.Handle("fn", func(request *yarf.Msg, response *yarf.Msg) error {
+		handlerSpan = trace.SpanContextFromContext(request.Context())
+		if _, ok := request.Headers["traceparent"].(string); !ok {
+			t.Error("expected traceparent header in request")
+		}
+		return yarf.NewRPCError(520, "failed")
+	})
+
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(middleware.OpenTelemetryClient(options))
+
+	err := client.Request("otel.fn").Done()
+	if err == nil {
+		t.Fatal("expected request to fail")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	serverSpan, clientSpan := spans[0], spans[1]
+
+	if clientSpan.SpanKind() != trace.SpanKindClient || serverSpan.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("expected client and server span, got %v and %v", clientSpan.SpanKind(), serverSpan.SpanKind())
+	}
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("expected server span to be a child of the client span")
+	}
+	if serverSpan.SpanContext().TraceID() != clientSpan.SpanContext().TraceID() {
+		t.Error("expected server and client span to be in the same trace")
+	}
+	if handlerSpan.SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Error("expected server span in the context of the request")
+	}
+	if serverSpan.Name() != "otel.fn" || serverSpan.Status().Code != codes.Error {
+		t.Errorf("expected failed span named otel.fn, got %s with status %v", serverSpan.Name(), serverSpan.Status())
+	}
+}
@@ -8,6 +8,9 @@ import (
 )
 
 // OpenTracing is a middleware for doing open tracing
+//
+// Deprecated: OpenTracing starts a new root span for every request, use OpenTelemetryClient and OpenTelemetryServer
+// which propagate spans from client to server
 func OpenTracing(prefix string) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
 
 	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
@@ -19,19 +22,23 @@ func OpenTracing(prefix string) func(request *yarf.Msg, response *yarf.Msg, next
 		span := opentracing.StartSpan(prefix + funcname)
 		defer span.Finish()
 
-		uuid, _ := request.UUID()
+		id, _ := request.RequestID()
+		parent, _ := request.ParentID()
 		span.LogFields(
 			log.String("type", "request"),
-			log.String("uuid", uuid),
+			log.String("uuid", id), // kept for existing dashboards and queries
+			log.String("request_id", id),
+			log.String("parent_id", parent),
 			log.Int("request_body_len", len(request.Content)),
 		)
 
 		err := next()
 
-		uuid, _ = response.UUID()
+		id, _ = response.RequestID()
 		span.LogFields(
 			log.String("type", "response"),
-			log.String("uuid", uuid),
+			log.String("uuid", id),
+			log.String("request_id", id),
 			log.Float64("response_duration", float64(time.Now().Sub(start).Nanoseconds())/1000000.0),
 			log.Int("response_body_len", len(response.Content)),
 		)
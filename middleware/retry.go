@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/modfin/yarf"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy defines when and how often a request is retried by the Retry middleware
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Defaults to 3
+	MaxAttempts int
+
+	// MaxElapsed is the maximum time spent on a request, including backoff, after which no more attempts are made. The
+	// deadline of the request context is always respected. Zero means no limit
+	MaxElapsed time.Duration
+
+	// InitialBackoff is the time waited before the first retry, defaults to 50ms. It is multiplied by Multiplier,
+	// defaulting to 2, for every retry up to MaxBackoff, defaulting to 5s
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter randomizes each backoff by up to the fraction given, e.g. 0.2 results in a backoff of 80% to 120% of the
+	// calculated one. Zero disables jitter
+	Jitter float64
+
+	// Statuses are the RPCError statuses that are retried, in addition to errors from the transport layer
+	Statuses []int
+
+	// NonIdempotent allows retrying requests that have not been marked idempotent, using RPC.WithIdempotent, which may
+	// result in a function being executed more than once
+	NonIdempotent bool
+}
+
+// DefaultRetryPolicy retries idempotent requests up to 3 times on transport errors
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// backoff returns the time to wait before the retry following the attempt, starting at 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= p.Multiplier
+	}
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// retryable returns true if err, or the status of the response, is one the policy retries
+func (p RetryPolicy) retryable(response *yarf.Msg, err error) bool {
+	status, ok := response.Status()
+
+	var rpcErr yarf.RPCError
+	if errors.As(err, &rpcErr) {
+		status, ok = rpcErr.Status, true
+	} else if err != nil {
+		return true
+	}
+
+	if !ok {
+		return false
+	}
+	for _, s := range p.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry is a client middleware that performs a request again if it fails with an error from the transport layer, or
+// with a status listed in the policy. Attempts are separated by an exponential backoff. Only requests marked as
+// idempotent are retried, unless the policy allows otherwise.
+func Retry(policy RetryPolicy) func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 50 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 5 * time.Second
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+
+	return func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+
+		if !policy.NonIdempotent && !request.Idempotent() {
+			return next()
+		}
+
+		ctx := request.Context()
+		start := time.Now()
+
+		for attempt := 1; ; attempt++ {
+			err := next()
+
+			if attempt >= policy.MaxAttempts || !policy.retryable(response, err) {
+				return err
+			}
+			if ctx != nil && ctx.Err() != nil {
+				return err
+			}
+
+			backoff := policy.backoff(attempt)
+			if policy.MaxElapsed > 0 && time.Since(start)+backoff > policy.MaxElapsed {
+				return err
+			}
+			if ctx != nil {
+				if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+					return err
+				}
+			}
+
+			timer := time.NewTimer(backoff)
+			if ctx != nil {
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				}
+			} else {
+				<-timer.C
+			}
+
+			// The response is unmarshalled into the same message by the next attempt
+			response.Headers = nil
+			response.Content = nil
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/middleware"
+	"github.com/modfin/yarf/transport/tmem"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRetryPair(t *testing.T, failures int64, handler func(request *yarf.Msg, response *yarf.Msg) error) (yarf.Client, *int64) {
+	var calls int64
+	transport, err := tmem.NewMemTransporter(tmem.Options{
+		Failure: func(function string) error {
+			if atomic.AddInt64(&calls, 1) <= failures {
+				return errors.New("transport failure")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := yarf.NewServer(transport, "retry")
+	server.Handle("fn", handler)
+
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(middleware.Retry(middleware.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Jitter:         0.5,
+		Statuses:       []int{503},
+	}))
+	return client, &calls
+}
+
+func ok(request *yarf.Msg, response *yarf.Msg) error {
+	response.SetParam("ok", true)
+	return nil
+}
+
+func TestRetry(t *testing.T) {
+	client, calls := newRetryPair(t, 2, ok)
+
+	msg, err := client.Request("retry.fn").WithIdempotent().Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Param("ok").BoolOr(false) {
+		t.Error("expected response of the last attempt")
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", *calls)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	client, calls := newRetryPair(t, 5, ok)
+
+	err := client.Request("retry.fn").WithIdempotent().Done()
+	if err == nil || err.Error() != "transport failure" {
+		t.Errorf("expected the last transport failure, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", *calls)
+	}
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	client, calls := newRetryPair(t, 2, ok)
+
+	err := client.Request("retry.fn").Done()
+	if err == nil {
+		t.Error("expected request that is not idempotent to fail")
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", *calls)
+	}
+}
+
+func TestRetryStatus(t *testing.T) {
+	var handled int64
+	client, _ := newRetryPair(t, 0, func(request *yarf.Msg, response *yarf.Msg) error {
+		n := atomic.AddInt64(&handled, 1)
+		if n == 1 {
+			return yarf.NewRPCError(503, "unavailable")
+		}
+		if n == 2 {
+			return yarf.NewRPCError(510, "failed")
+		}
+		return nil
+	})
+
+	err := client.Request("retry.fn").WithIdempotent().Done()
+	rpcErr, ok := err.(yarf.RPCError)
+	if !ok || rpcErr.Status != 510 {
+		t.Errorf("expected status 510 not to be retried, got %v", err)
+	}
+	if handled != 2 {
+		t.Errorf("expected 2 attempts, got %d", handled)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{
+		Failure: func(function string) error { return errors.New("transport failure") },
+	})
+	client := yarf.NewClient(transport)
+	client.WithMiddleware(middleware.Retry(middleware.RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Request("retry.fn").WithIdempotent().WithContext(ctx).Done()
+	if err == nil {
+		t.Error("expected request to fail")
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Errorf("expected no backoff past the deadline, took %v", time.Since(start))
+	}
+}
@@ -3,7 +3,7 @@ package yarf
 import (
 	"context"
 	"errors"
-	"fmt"
+	"time"
 )
 
 // StatusOk rpc status ok
@@ -24,33 +24,98 @@ const StatusMarshalError = 550
 // StatusUnmarshalError could not unmarshal data
 const StatusUnmarshalError = 551
 
+// StatusUnauthorized the request lacks valid credentials
+const StatusUnauthorized = 541
+
+// StatusForbidden the caller is not allowed to perform the request
+const StatusForbidden = 543
+
+// StatusInvalidSignature the signature of the request is missing, invalid or outside of the time window accepted
+const StatusInvalidSignature = 544
+
+// StatusReplayed the request has already been received, and is rejected as a replay
+const StatusReplayed = 545
+
+// StatusFunctionNotFound no handler is listening to the function requested
+const StatusFunctionNotFound = 552
+
+// StatusDecryptError the message could not be decrypted, e.g. since it is encrypted with an unknown key
+const StatusDecryptError = 553
+
+// StatusCircuitOpen the request was not performed since the circuit breaker of the function is open
+const StatusCircuitOpen = 560
+
+// StatusTooManyRequests the request was rejected since a rate or concurrency limit of the server was exceeded
+const StatusTooManyRequests = 529
+
 // HeaderStatus is the status header param name
 const HeaderStatus = "status"
 
-// HeaderUUID is the uuid header param name
+// HeaderUUID is the header param name the request id was sent in by earlier versions of yarf.
+//
+// Deprecated: HeaderUUID collides with HeaderStatus, use HeaderRequestID. It is only read from requests of peers not
+// sending HeaderRequestID
 const HeaderUUID = "status"
 
+// HeaderRequestID is the id of the request, which is generated by the client unless set and echoed in the response
+const HeaderRequestID = "request-id"
+
+// HeaderParentID is the id of the request that caused the request to be made, if it was made while handling another
+// request, which makes it possible to follow a chain of calls
+const HeaderParentID = "parent-id"
+
 // HeaderFunction is the function name header param name
 const HeaderFunction = "function"
 
 // HeaderContentType is the function name header param name
 const HeaderContentType = "content-type"
 
+// HeaderStreamEnd is set on the final message of a stream of responses
+const HeaderStreamEnd = "stream-end"
+
+// HeaderTimeout is the remaining time budget of the request in milliseconds, derived from the deadline of the client
+// context. The budget is sent rather than the deadline itself, so that clocks of client and server need not be in sync
+const HeaderTimeout = "timeout"
+
+// HeaderIdempotent marks a request as safe to perform more than once, e.g. by retrying it
+const HeaderIdempotent = "idempotent"
+
+// HeaderContentEncoding is the encoding of the compressed content of a message, e.g. gzip
+const HeaderContentEncoding = "content-encoding"
+
+// HeaderAcceptEncoding lists the encodings, separated by comma, that the client accepts responses compressed with
+const HeaderAcceptEncoding = "accept-encoding"
+
 // Msg represents a message that is being passed between client and server
 type Msg struct {
 	ctx                context.Context
 	protocolSerializer Serializer
 	contentSerializer  Serializer
+	compression        *compression
+
+	// contentCompressed is set once the content has been compressed using CompressContent, and is not compressed again
+	// when marshalled
+	contentCompressed bool
 
 	builderError error
 
+	send func(data []byte) error
+
 	Headers map[string]interface{}
 	Content []byte
 }
 
 func (m *Msg) doMarshal() (data []byte, err error) {
+	msg := m
+	if !m.contentCompressed {
+		msg, err = m.compressed()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	contentType := []byte(m.protocolSerializer.ContentType + "\n")
-	content, err := m.protocolSerializer.Marshal(m)
+	content, err := m.protocolSerializer.Marshal(msg)
 
 	data = make([]byte, 0, len(contentType)+len(content))
 	data = append(data, contentType...)
@@ -61,6 +126,22 @@ func (m *Msg) doMarshal() (data []byte, err error) {
 }
 
 func (m *Msg) doUnmarshal(data []byte) (err error) {
+	ser, data, err := protocolSerializerOf(data)
+	if err != nil {
+		return err
+	}
+
+	err = ser.Unmarshal(data, m)
+	if err != nil {
+		return err
+	}
+
+	return m.decompress()
+}
+
+// protocolSerializerOf returns the protocol serializer of a marshaled message, as given by its first line, and the data
+// following it
+func protocolSerializerOf(data []byte) (Serializer, []byte, error) {
 	contentlen := -1
 
 	for i := 0; i < 100 && i < len(data); i++ {
@@ -71,7 +152,7 @@ func (m *Msg) doUnmarshal(data []byte) (err error) {
 	}
 
 	if contentlen == -1 {
-		return errors.New("Could not find content type")
+		return Serializer{}, nil, errors.New("Could not find content type")
 	}
 
 	contentType, data := string(data[:contentlen]), data[contentlen+1:]
@@ -79,11 +160,9 @@ func (m *Msg) doUnmarshal(data []byte) (err error) {
 	ser, ok := serializer(contentType)
 
 	if !ok {
-		return errors.New("could not find a suitable protocolSerializer")
+		return Serializer{}, nil, errors.New("could not find a suitable protocolSerializer")
 	}
-
-	err = ser.Unmarshal(data, m)
-	return
+	return ser, data, nil
 }
 
 // BindContent is used to unmarshal/bind content data to input interface. It will look for a proper deserializer matching
@@ -140,12 +219,58 @@ func (m *Msg) Function() (status string, ok bool) {
 	return
 }
 
-// UUID returns the request uuid
+// UUID returns the request id
+//
+// Deprecated: use RequestID
 func (m *Msg) UUID() (status string, ok bool) {
-	status, ok = m.Headers[HeaderUUID].(string)
+	return m.RequestID()
+}
+
+// RequestID returns the request id of the message, if one exist. Requests of peers sending the id in the legacy
+// HeaderUUID are supported as well
+func (m *Msg) RequestID() (id string, ok bool) {
+	id, ok = m.Headers[HeaderRequestID].(string)
+	if ok {
+		return
+	}
+	// The status header is numeric unless it holds the id of a request sent by an earlier version of yarf
+	id, ok = m.Headers[HeaderUUID].(string)
+	return
+}
+
+// SetRequestID sets the request id header of the message
+func (m *Msg) SetRequestID(id string) *Msg {
+	m.SetHeader(HeaderRequestID, id)
+	return m
+}
+
+// ParentID returns the id of the request that caused the request, if one exist
+func (m *Msg) ParentID() (id string, ok bool) {
+	id, ok = m.Headers[HeaderParentID].(string)
 	return
 }
 
+// SetParentID sets the parent id header of the message
+func (m *Msg) SetParentID(id string) *Msg {
+	m.SetHeader(HeaderParentID, id)
+	return m
+}
+
+// Timeout returns the remaining time budget of the request, as sent by the client, if one exist
+func (m *Msg) Timeout() (timeout time.Duration, ok bool) {
+	ms, ok := toInt(m.Headers[HeaderTimeout])
+	if ok {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	return
+}
+
+// Idempotent returns true if the request is marked as safe to perform more than once
+func (m *Msg) Idempotent() bool {
+	idempotent, _ := m.Headers[HeaderIdempotent].(bool)
+	return idempotent
+}
+
 // Ok sets the status header to 200
 func (m *Msg) Ok() *Msg {
 	return m.SetStatus(StatusOk)
@@ -166,8 +291,12 @@ func (m *Msg) SetHeader(key string, value interface{}) *Msg {
 	return m
 }
 
-// SetContent sets the input interface as the content of the message
+// SetContent sets the input interface as the content of the message. If no contentSerializer has been set on the
+// message, e.g. when created by the user, the default one is used
 func (m *Msg) SetContent(content interface{}) *Msg {
+	if m.contentSerializer.Marshal == nil {
+		m.contentSerializer = defaultSerializer()
+	}
 	m.SetContentUsing(content, m.contentSerializer)
 	return m
 }
@@ -182,7 +311,7 @@ func (m *Msg) SetContentUsing(content interface{}, serializer Serializer) *Msg {
 	if err != nil {
 		m.builderError = err
 		// TODO should panic? set an internal error?
-		fmt.Println("Could not set SetContentUsing", err)
+		Logger().Error("could not marshal content", "content_type", serializer.ContentType, "error", err)
 	}
 	return m
 }
@@ -194,6 +323,32 @@ func (m *Msg) SetBinaryContent(content []byte) *Msg {
 	return m
 }
 
+// Send streams msg to the client as one in a sequence of responses to a request. It may only be used on the response
+// message passed to a handler and only if the request was made using RPC.Stream, otherwise an error is returned.
+// Once the handler returns, the response message itself is sent as the final message of the stream.
+func (m *Msg) Send(msg *Msg) error {
+	if m.send == nil {
+		return errors.New("the request is not streaming responses")
+	}
+	if msg.protocolSerializer.Marshal == nil {
+		msg.protocolSerializer = m.protocolSerializer
+	}
+	if msg.compression == nil {
+		msg.compression = m.compression
+	}
+
+	data, err := msg.doMarshal()
+	if err != nil {
+		return err
+	}
+	return m.send(data)
+}
+
+// IsStreaming returns true if the message is a response where a stream of messages can be sent using Send
+func (m *Msg) IsStreaming() bool {
+	return m.send != nil
+}
+
 // Context returns the context of the message. This is primarily for use on the server side, in order to monitor Done from client side
 func (m *Msg) Context() context.Context {
 	return m.ctx
@@ -0,0 +1,18 @@
+package yarf
+
+import "context"
+
+// WithRequestID returns a copy of ctx carrying the request id. Servers add the id of the request being handled to its
+// context, and requests made using a context carrying an id are sent with it as their parent id, see HeaderParentID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id carried by ctx, if any
+func RequestIDFromContext(ctx context.Context) (id string, ok bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok = ctx.Value(requestIDKey).(string)
+	return
+}
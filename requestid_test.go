@@ -0,0 +1,79 @@
+package yarf_test
+
+import (
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "requestid")
+	client := yarf.NewClient(transport)
+
+	var parent, child, childParent string
+	server.Handle("parent", func(request *yarf.Msg, response *yarf.Msg) error {
+		parent, _ = yarf.RequestIDFromContext(request.Context())
+		response.Ok()
+		return client.Request("requestid.child").WithContext(request.Context()).Done()
+	})
+	server.Handle("child", func(request *yarf.Msg, response *yarf.Msg) error {
+		child, _ = request.RequestID()
+		childParent, _ = request.ParentID()
+		return nil
+	})
+
+	res, err := client.Request("requestid.parent").WithRequestID("root").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := res.RequestID(); id != "root" {
+		t.Errorf("expected response to carry request id root, got %q", id)
+	}
+	if status, _ := res.Status(); status != yarf.StatusOk {
+		t.Errorf("expected status %d not to be overwritten by the request id, got %d", yarf.StatusOk, status)
+	}
+	if parent != "root" {
+		t.Errorf("expected request id root in the context of the handler, got %q", parent)
+	}
+	if child == "" || child == "root" {
+		t.Errorf("expected chained request to get an id of its own, got %q", child)
+	}
+	if childParent != "root" {
+		t.Errorf("expected chained request to have parent id root, got %q", childParent)
+	}
+}
+
+func TestRequestIDLegacyHeader(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "requestid")
+	client := yarf.NewClient(transport)
+
+	// Sends the request id the way earlier versions of yarf did
+	client.WithMiddleware(func(request *yarf.Msg, response *yarf.Msg, next yarf.NextMiddleware) error {
+		delete(request.Headers, yarf.HeaderRequestID)
+		request.SetHeader(yarf.HeaderUUID, "legacy")
+		return next()
+	})
+
+	var received string
+	server.Handle("echo", func(request *yarf.Msg, response *yarf.Msg) error {
+		received, _ = request.RequestID()
+		response.Ok()
+		return nil
+	})
+
+	res, err := client.Request("requestid.echo").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received != "legacy" {
+		t.Errorf("expected request id legacy to be read from the legacy header, got %q", received)
+	}
+	if id, _ := res.RequestID(); id != "legacy" {
+		t.Errorf("expected response to carry request id legacy, got %q", id)
+	}
+	if status, _ := res.Status(); status != yarf.StatusOk {
+		t.Errorf("expected status %d, got %d", yarf.StatusOk, status)
+	}
+}
@@ -2,9 +2,11 @@ package yarf
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,6 +17,9 @@ type Server struct {
 	middleware         []Middleware
 	protocolSerializer Serializer
 	contentSerializer  Serializer
+	registry           *registry
+	health             *health
+	compression        *compression
 }
 
 // NewServer creates a new server with a particular server and name space of functions provided
@@ -28,6 +33,9 @@ func NewServer(t Transporter, namespace ...string) Server {
 	}
 	s.protocolSerializer = defaultSerializer()
 	s.contentSerializer = defaultSerializer()
+	s.registry = &registry{}
+	s.health = newHealth()
+	s.listenBuiltins()
 	return s
 }
 
@@ -45,16 +53,35 @@ func toServerError(status int, response *Msg, errors ...string) (responseData []
 // WithMiddleware add middleware to all requests
 func (s *Server) WithMiddleware(middleware ...Middleware) {
 	s.middleware = append(s.middleware, middleware...)
+	s.configured()
 }
 
 // WithProtocolSerializer sets the protocolSerializer used for transport.
 func (s *Server) WithProtocolSerializer(serializer Serializer) {
 	s.protocolSerializer = serializer
+	s.configured()
 }
 
 // WithSerializer sets the default protocolSerializer for content.
 func (s *Server) WithSerializer(serializer Serializer) {
 	s.contentSerializer = serializer
+	s.configured()
+}
+
+// WithCompression compresses the content of responses of at least threshold bytes using the compressor, where a
+// threshold of zero defaults to DefaultCompressionThreshold. Responses are only compressed for clients accepting the
+// encoding of the compressor, while compressed requests are always decompressed
+func (s *Server) WithCompression(compressor Compressor, threshold int) {
+	s.compression = newCompression(compressor, threshold)
+	s.configured()
+}
+
+// responseCompression returns the compression of responses to the request
+func (s *Server) responseCompression(request *Msg) *compression {
+	if s.compression == nil || !accepts(request, s.compression.compressor.Encoding) {
+		return nil
+	}
+	return s.compression
 }
 
 // HandleFunc creates a server endpoint for yarf using the handler function, the name of function will be on the format "namespace.FunctionName"
@@ -72,37 +99,165 @@ func (s *Server) Handle(function string, handler func(request *Msg, response *Ms
 		function = s.namespace + "." + function
 	}
 
+	s.register(function, false)
+	s.listen(function, handler, middleware)
+}
+
+// listen registers the handler of the function with the transporter
+func (s *Server) listen(function string, handler func(request *Msg, response *Msg) error, middleware []Middleware) {
 	_ = s.transporter.Listen(function, func(ctx context.Context, requestData []byte) (responseData []byte) {
+		return s.exec(ctx, function, requestData, nil, handler, middleware)
+	})
 
-		req := Msg{} // Automatically find deserializer
-		resp := Msg{protocolSerializer: s.protocolSerializer, contentSerializer: s.contentSerializer}
+	if transporter, ok := s.transporter.(ServerStreamTransporter); ok {
+		_ = transporter.ListenStream(function, func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte) {
+			return s.exec(ctx, function, requestData, send, handler, middleware)
+		})
+	}
+}
 
-		err := req.doUnmarshal(requestData)
-		if err != nil {
-			return toServerError(StatusUnmarshalError, &resp, err.Error())
-		}
-		req.ctx = ctx
+// exec unmarshal the request, runs it through middleware and the handler and returns the marshaled response. If send is
+// provided, the handler may stream messages using it and the response is marked as the end of the stream.
+func (s *Server) exec(ctx context.Context, function string, requestData []byte, send func(data []byte) error, handler func(request *Msg, response *Msg) error, middleware []Middleware) (responseData []byte) {
 
-		resp.SetHeader(HeaderUUID, req.Headers[HeaderUUID])
+	req := Msg{} // Automatically find deserializer
+	resp := Msg{protocolSerializer: s.protocolSerializer, contentSerializer: s.contentSerializer}
 
-		err = processMiddleware(&req, &resp, handler, append(s.middleware, middleware...)...)
+	if send != nil {
+		resp.SetHeader(HeaderStreamEnd, true)
+	}
 
-		if err != nil {
-			err2, ok := err.(RPCError)
-			if ok {
-				return toServerErrorFrom(err2, &resp)
+	err := req.doUnmarshal(requestData)
+	if err != nil {
+		return toServerError(StatusUnmarshalError, &resp, err.Error())
+	}
+
+	// The function header is set by the client, and is replaced by the function dispatched to so that middleware, e.g.
+	// authorization policies, can not be deceived by a header naming another function
+	req.SetHeader(HeaderFunction, function)
+
+	ctx, cancel := withRequestTimeout(ctx, &req)
+	defer cancel()
+
+	if id, ok := req.RequestID(); ok {
+		resp.SetRequestID(id)
+		ctx = WithRequestID(ctx, id)
+	}
+	req.ctx = ctx
+	resp.compression = s.responseCompression(&req)
+
+	if send != nil {
+		var mu sync.Mutex
+		var done bool
+		resp.send = func(data []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if done {
+				return errors.New("the stream has been closed")
 			}
+			return send(data)
+		}
+		defer func() {
+			mu.Lock()
+			done = true
+			mu.Unlock()
+		}()
+	}
+
+	err = processMiddleware(&req, &resp, handler, append(s.middleware, middleware...)...)
 
-			return toServerError(StatusHandlerError, &resp, err.Error())
+	return toServerResponse(&resp, err)
+}
+
+// withRequestTimeout applies the time budget sent by the client to the context of the request, so that the handler
+// is able to stop working once the client has given up, and pass the remaining budget on to downstream requests
+func withRequestTimeout(ctx context.Context, request *Msg) (context.Context, context.CancelFunc) {
+	timeout, ok := request.Timeout()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// toServerResponse marshals the response, or the error returned by the handler and middleware if there is one
+func toServerResponse(response *Msg, err error) (responseData []byte) {
+	if err != nil {
+		err2, ok := err.(RPCError)
+		if ok {
+			return toServerErrorFrom(err2, response)
 		}
 
-		responseData, err = resp.doMarshal()
+		return toServerError(StatusHandlerError, response, err.Error())
+	}
+
+	responseData, err = response.doMarshal()
+
+	if err != nil {
+		return toServerError(StatusMarshalError, response, err.Error())
+	}
+
+	return responseData
+}
+
+// HandleStream creates a server endpoint for yarf where the client and handler are able to send sequences of messages
+// to each other over a Stream, the name of function will be on the format "namespace.function". The stream is opened
+// by the client using Client.Stream and is only supported by transporters implementing StreamTransporter. Middleware
+// is run with the message the stream was opened with as request and the final message of the stream as response.
+// Using other transporters, the function is not handled and an error is logged through Logger.
+func (s *Server) HandleStream(function string, handler func(stream *Stream) error, middleware ...Middleware) {
+	if s.namespace != "" {
+		function = s.namespace + "." + function
+	}
+
+	transporter, ok := s.transporter.(StreamTransporter)
+	if !ok {
+		Logger().Error("could not handle stream, the transporter does not support streams", "function", function)
+		return
+	}
+
+	s.register(function, true)
+
+	_ = transporter.ListenDuplex(function, func(ctx context.Context, transport TransportStream) {
+
+		req := Msg{} // Automatically find deserializer
+		resp := Msg{protocolSerializer: s.protocolSerializer, contentSerializer: s.contentSerializer}
+		resp.SetHeader(HeaderStreamEnd, true)
+
+		requestData, err := transport.Recv()
+		if err != nil {
+			_ = transport.Send(toServerError(StatusUnmarshalError, &resp, err.Error()))
+			return
+		}
 
+		err = req.doUnmarshal(requestData)
 		if err != nil {
-			return toServerError(StatusMarshalError, &resp, err.Error())
+			_ = transport.Send(toServerError(StatusUnmarshalError, &resp, err.Error()))
+			return
 		}
+		req.SetHeader(HeaderFunction, function)
+
+		ctx, cancel := withRequestTimeout(ctx, &req)
+		defer cancel()
+
+		if id, ok := req.RequestID(); ok {
+			resp.SetRequestID(id)
+			ctx = WithRequestID(ctx, id)
+		}
+		req.ctx = ctx
+		resp.compression = s.responseCompression(&req)
+
+		err = processMiddleware(&req, &resp, func(request *Msg, response *Msg) error {
+			return handler(&Stream{
+				ctx:                request.Context(),
+				transport:          transport,
+				request:            request,
+				protocolSerializer: s.protocolSerializer,
+				contentSerializer:  s.contentSerializer,
+				compression:        resp.compression,
+			})
+		}, append(s.middleware, middleware...)...)
 
-		return responseData
+		_ = transport.Send(toServerResponse(&resp, err))
 	})
 }
 
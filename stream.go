@@ -0,0 +1,172 @@
+package yarf
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// RPCStream is an iterator over a sequence of responses streamed from a server, created by RPC.Stream.
+// Messages are received one at a time when calling Next, giving back-pressure towards the server.
+type RPCStream struct {
+	rpc    *RPC
+	cancel func()
+
+	msgs chan *Msg
+	done chan struct{}
+
+	msg *Msg
+
+	mu  sync.Mutex
+	err error
+}
+
+// Stream performs the request and returns an iterator over the sequence of responses the server sends using Msg.Send.
+// The final response from the handler, with its status, is bound as a regular response and can be retrieved using
+// Get once Next has returned false. Cancellation of the stream is done through the request context or by Close.
+func (r *RPC) Stream() *RPCStream {
+	s := &RPCStream{
+		rpc:    r,
+		cancel: func() {},
+		msgs:   make(chan *Msg),
+		done:   make(chan struct{}),
+	}
+
+	transporter, ok := r.client.transporter.(ServerStreamTransporter)
+	if !ok {
+		err := errors.New("transporter does not support streaming")
+		// The request is finished with the error, so that it is returned by Get rather than Get waiting forever
+		if r.stateEq(builderState) {
+			r.setState(finishedState)
+			r.mutex.Lock()
+			r.err = err
+			r.mutex.Unlock()
+			close(r.done)
+		}
+		s.finish(err)
+		return s
+	}
+
+	if !r.stateEq(builderState) {
+		s.finish(errors.New("request has already been performed"))
+		return s
+	}
+	r.setState(transitState)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s.cancel = r.prepare()
+
+	go func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		defer func() {
+			s.cancel()
+			r.setState(finishedState)
+			close(r.done)
+			s.finish(r.err)
+		}()
+
+		r.setState(requestState)
+
+		r.err = processMiddleware(r.requestMsg, r.responseMsg, toClientStreamHandler(r, transporter, s.msgs), append(r.client.middleware, r.middleware...)...)
+
+		if r.err == nil {
+			r.err = r.doBind(r.requestMsg, r.responseMsg)
+		}
+
+		r.setState(responseState)
+	}()
+
+	return s
+}
+
+func toClientStreamHandler(r *RPC, transporter ServerStreamTransporter, msgs chan<- *Msg) func(request *Msg, response *Msg) error {
+	return func(request *Msg, response *Msg) error {
+		setTimeoutHeader(r.ctx, request)
+
+		reqBytes, err := request.doMarshal()
+		if err != nil {
+			return err
+		}
+
+		receiver, err := transporter.CallStream(r.ctx, r.function, reqBytes)
+		if err != nil {
+			return err
+		}
+		defer receiver.Close()
+
+		for {
+			data, err := receiver.Recv()
+			if err == io.EOF {
+				return errors.New("stream ended without a final response")
+			}
+			if err != nil {
+				return err
+			}
+
+			msg := &Msg{ctx: r.ctx}
+			err = msg.doUnmarshal(data)
+			if err != nil {
+				return err
+			}
+
+			if end, _ := msg.Headers[HeaderStreamEnd].(bool); end {
+				*response = *msg
+				return nil
+			}
+
+			select {
+			case msgs <- msg:
+			case <-r.ctx.Done():
+				return r.ctx.Err()
+			}
+		}
+	}
+}
+
+func (s *RPCStream) finish(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	close(s.done)
+}
+
+// Next blocks until the next message of the stream is received and returns true, or returns false when the stream has
+// ended or failed. The message is retrieved by Msg
+func (s *RPCStream) Next() bool {
+	select {
+	case msg := <-s.msgs:
+		s.msg = msg
+		return true
+	case <-s.done:
+		s.msg = nil
+		return false
+	}
+}
+
+// Msg returns the message received by the last call to Next
+func (s *RPCStream) Msg() *Msg {
+	return s.msg
+}
+
+// Err returns the error terminating the stream, if any. It shall be checked once Next returns false, and will return
+// an RPCError if the server handler failed.
+func (s *RPCStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close cancels the stream if it is still in flight and waits for it to be done
+func (s *RPCStream) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Done returns a channel that is closed when the stream has ended
+func (s *RPCStream) Done() <-chan struct{} {
+	return s.done
+}
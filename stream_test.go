@@ -0,0 +1,37 @@
+package yarf_test
+
+import (
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+	"time"
+)
+
+func TestStreamNotSupported(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	// Hides the streaming of the transporter
+	client := yarf.NewClient(struct{ yarf.Transporter }{transport})
+
+	rpc := client.Request("stream.numbers")
+	stream := rpc.Stream()
+	if stream.Next() {
+		t.Fatal("expected no messages")
+	}
+	if stream.Err() == nil {
+		t.Error("expected the stream to fail")
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := rpc.Get()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected Get to return the error of the stream")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Get to return once the stream has failed")
+	}
+}
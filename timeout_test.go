@@ -0,0 +1,39 @@
+package yarf_test
+
+import (
+	"context"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "timeout")
+	client := yarf.NewClient(transport)
+	client.WithDefaultTimeout(50 * time.Millisecond)
+
+	server.Handle("sleep", func(request *yarf.Msg, response *yarf.Msg) error {
+		select {
+		case <-time.After(time.Duration(request.Param("ms").IntOr(0)) * time.Millisecond):
+		case <-request.Context().Done():
+		}
+		return nil
+	})
+
+	err := client.Request("timeout.sleep").WithParam("ms", 200).Done()
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected default timeout to be exceeded, got %v", err)
+	}
+
+	err = client.Request("timeout.sleep").WithParam("ms", 100).WithTimeout(time.Second).Done()
+	if err != nil {
+		t.Errorf("expected request timeout to override the default, got %v", err)
+	}
+
+	err = client.Request("timeout.sleep").WithParam("ms", 200).WithTimeout(20 * time.Millisecond).Done()
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected request timeout to be exceeded, got %v", err)
+	}
+}
@@ -19,6 +19,46 @@ type Transporter interface {
 	CloseGraceful(timeout time.Duration) error
 }
 
+// ServerStreamTransporter is an optional interface that a Transporter may implement in order to support server side
+// streaming, i.e. a handler sending a sequence of responses to a single request.
+type ServerStreamTransporter interface {
+	CallStream(ctx context.Context, function string, requestData []byte) (StreamReceiver, error)
+	ListenStream(function string, toExec func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte)) error
+}
+
+// StreamReceiver is returned by a ServerStreamTransporter when calling a streaming function. Recv blocks until the next
+// message of the stream is received and returns io.EOF after the last one. Close shall be called when done with the
+// stream, and cancels it if it is still in flight.
+type StreamReceiver interface {
+	Recv() (data []byte, err error)
+	Close() error
+}
+
+// StreamTransporter is an optional interface that a Transporter may implement in order to support client side and
+// bidirectional streaming, i.e. both client and handler sending sequences of messages over the same call.
+type StreamTransporter interface {
+	CallDuplex(ctx context.Context, function string) (TransportStream, error)
+	ListenDuplex(function string, toExec func(ctx context.Context, stream TransportStream)) error
+}
+
+// TransportStream is a bidirectional stream of messages provided by a StreamTransporter. Recv returns io.EOF once the
+// other side has closed its sending direction, which is done by CloseSend. On the server side, the stream is closed by
+// the transporter once toExec returns. On the client side, Close shall be called when done with the stream and cancels
+// it if it is still in flight.
+type TransportStream interface {
+	Send(data []byte) error
+	Recv() (data []byte, err error)
+	CloseSend() error
+	Close() error
+}
+
+// HealthTransporter is an optional interface that a Transporter may implement in order to expose the health of the
+// servers using it by means of its own, e.g. a http endpoint for probes. It is called once for every server, with a
+// func returning the current health of the server.
+type HealthTransporter interface {
+	ListenHealth(namespace string, health func() Health)
+}
+
 // Serializer is the interface that must be fulfilled for protocolSerializer of data before transport.
 type Serializer struct {
 	ContentType string
@@ -2,8 +2,9 @@ package thttp
 
 import (
 	"errors"
-	"fmt"
 	"github.com/miekg/dns"
+	"github.com/modfin/yarf"
+	"log/slog"
 	"math/rand"
 	"net"
 	"sync"
@@ -35,6 +36,9 @@ type DiscoveryDNSA struct {
 
 	Resolv string
 
+	// Logger is used to report failures to resolve the host, defaults to yarf.Logger()
+	Logger *slog.Logger
+
 	lock       sync.Mutex
 	updatelock sync.Mutex
 	pos        int
@@ -49,7 +53,7 @@ func (d *DiscoveryDNSA) refresh() {
 
 	config, err := dns.ClientConfigFromFile(stringOr(d.Resolv, "/etc/resolv.conf"))
 	if err != nil {
-		fmt.Println("Could not connect to resolver", err)
+		d.logger().Error("could not read resolver config", "resolv", stringOr(d.Resolv, "/etc/resolv.conf"), "error", err)
 		return
 	}
 
@@ -62,17 +66,17 @@ func (d *DiscoveryDNSA) refresh() {
 	r, _, err := c.Exchange(m, net.JoinHostPort(config.Servers[0], config.Port))
 
 	if err != nil {
-		fmt.Println("Could not query to resolver", err)
+		d.logger().Error("could not query resolver", "host", d.Host, "error", err)
 		return
 	}
 
 	if r == nil {
-		fmt.Println("Could not query to resolver", err)
+		d.logger().Error("could not query resolver", "host", d.Host)
 		return
 	}
 
 	if r.Rcode != dns.RcodeSuccess {
-		fmt.Println("Invalid answer from resolver", err)
+		d.logger().Warn("invalid answer from resolver", "host", d.Host, "rcode", dns.RcodeToString[r.Rcode])
 		return
 	}
 	// Stuff must be in the answer section
@@ -96,6 +100,13 @@ func (d *DiscoveryDNSA) refresh() {
 
 }
 
+func (d *DiscoveryDNSA) logger() *slog.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return yarf.Logger()
+}
+
 // URL implements the Discovery interface
 func (d *DiscoveryDNSA) URL() (string, error) {
 
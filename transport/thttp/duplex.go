@@ -0,0 +1,198 @@
+package thttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// httpDuplexStream is a bidirectional stream over a single http request, where frames are sent in the request body and
+// received in the response body concurrently. It is used on both the client and server side.
+type httpDuplexStream struct {
+	ctx    context.Context
+	cancel func()
+
+	sendMu sync.Mutex
+	writer io.Writer
+	flush  func() error
+	closer io.Closer
+	sent   bool
+
+	reader *bufio.Reader
+	ended  bool
+	body   io.Closer
+}
+
+// CallDuplex implements client side call of a bidirectional streaming function
+func (h *HTTPTransporter) CallDuplex(ctx context.Context, function string) (yarf.TransportStream, error) {
+
+	if h.IsClose() {
+		return nil, errors.New("transport layer is has been closed")
+	}
+
+	url, err := h.options.Discovery.URL()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	pr, pw := io.Pipe()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url+"/"+function, pr)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("content-type", streamContentType)
+	req.Header.Set(headerStream, streamDuplex)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		cancel()
+		pw.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("content-type") != streamContentType {
+		cancel()
+		pw.Close()
+		defer resp.Body.Close()
+		return nil, streamError(resp)
+	}
+
+	return &httpDuplexStream{
+		ctx:    ctx,
+		cancel: cancel,
+		writer: pw,
+		flush:  func() error { return nil },
+		closer: pw,
+		reader: bufio.NewReader(resp.Body),
+		body:   resp.Body,
+	}, nil
+}
+
+// ListenDuplex defines the function that will handle yarf bidirectional streaming requests
+func (h *HTTPTransporter) ListenDuplex(function string, toExec func(ctx context.Context, stream yarf.TransportStream)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handle(function)
+	h.duplexes[function] = toExec
+	return nil
+}
+
+func (h *HTTPTransporter) serveDuplex(function string, res http.ResponseWriter, req *http.Request) {
+
+	h.mu.Lock()
+	toExec := h.duplexes[function]
+	h.mu.Unlock()
+
+	if toExec == nil {
+		writeError(res, http.StatusNotFound, yarf.StatusFunctionNotFound, "no stream "+function+" is listened to")
+		return
+	}
+
+	rc := http.NewResponseController(res)
+	err := rc.EnableFullDuplex()
+	if err != nil {
+		writeError(res, http.StatusHTTPVersionNotSupported, yarf.StatusInternalError, "could not open a full duplex stream, "+err.Error())
+		return
+	}
+
+	// A stream may live longer than the server read and write timeouts, which are intended for a single request
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	res.Header().Set("content-type", streamContentType)
+	res.WriteHeader(http.StatusOK)
+	err = rc.Flush()
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	stream := &httpDuplexStream{
+		ctx:    ctx,
+		cancel: cancel,
+		writer: res,
+		flush:  rc.Flush,
+		reader: bufio.NewReader(req.Body),
+		body:   req.Body,
+	}
+
+	toExec(ctx, stream)
+
+	_ = stream.CloseSend()
+}
+
+// Send implements yarf.TransportStream
+func (s *httpDuplexStream) Send(data []byte) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.sent {
+		return errors.New("stream has been closed for sending")
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	err := writeFrame(s.writer, data)
+	if err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// Recv implements yarf.TransportStream
+func (s *httpDuplexStream) Recv() ([]byte, error) {
+	if s.ended {
+		return nil, io.EOF
+	}
+	data, err := readFrame(s.reader)
+	if err == io.EOF && data == nil {
+		s.ended = true
+	}
+	if err != nil && err != io.EOF && s.ctx.Err() != nil {
+		return nil, s.ctx.Err()
+	}
+	return data, err
+}
+
+// CloseSend implements yarf.TransportStream
+func (s *httpDuplexStream) CloseSend() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.sent {
+		return nil
+	}
+	s.sent = true
+
+	err := writeFrame(s.writer, nil)
+	if err == nil {
+		err = s.flush()
+	}
+	if s.closer != nil {
+		_ = s.closer.Close()
+	}
+	return err
+}
+
+// Close implements yarf.TransportStream
+func (s *httpDuplexStream) Close() error {
+	s.cancel()
+	if s.closer != nil {
+		_ = s.closer.Close()
+	}
+	return s.body.Close()
+}
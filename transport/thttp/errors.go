@@ -0,0 +1,94 @@
+package thttp
+
+import (
+	"github.com/modfin/yarf"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// contentType is the http content type of a response containing a yarf message
+const contentType = "application/x-yarf"
+
+// maxErrorBody is the number of bytes of a response body that are kept in a HTTPError
+const maxErrorBody = 512
+
+// HTTPError is returned by the client when the server responds with something that is not a yarf message, e.g. the
+// error page of a proxy or load balancer in between
+type HTTPError struct {
+	StatusCode int
+	Status     string
+
+	// Body is the beginning of the response body, up to 512 bytes
+	Body []byte
+}
+
+func newHTTPError(resp *http.Response, body []byte) HTTPError {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	return HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+}
+
+func (e HTTPError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = strconv.Itoa(e.StatusCode)
+	}
+	return "unexpected http response, " + status
+}
+
+// httpStatus maps the status of a yarf response to the http status code it is sent with. Statuses specific to yarf are
+// mapped to their closest http equivalent, while statuses known to http are kept as is
+func httpStatus(status int) int {
+	switch status {
+	case yarf.StatusOk:
+		return http.StatusOK
+	case yarf.StatusUnmarshalError, yarf.StatusDecryptError:
+		return http.StatusBadRequest
+	case yarf.StatusFunctionNotFound:
+		return http.StatusNotFound
+	case yarf.StatusUnauthorized:
+		return http.StatusUnauthorized
+	case yarf.StatusForbidden:
+		return http.StatusForbidden
+	case yarf.StatusInvalidSignature:
+		return http.StatusUnauthorized
+	case yarf.StatusReplayed:
+		return http.StatusConflict
+	case yarf.StatusTooManyRequests:
+		return http.StatusTooManyRequests
+	case yarf.StatusCircuitOpen:
+		return http.StatusServiceUnavailable
+	case yarf.StatusInternalError, yarf.StatusInternalPanic, yarf.StatusHandlerError, yarf.StatusMarshalError:
+		return http.StatusInternalServerError
+	}
+
+	if http.StatusText(status) != "" {
+		return status
+	}
+	if status >= 500 {
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// writeError responds with a yarf message containing the error, which is returned to the client as is
+func writeError(res http.ResponseWriter, code int, status int, msg string) {
+	res.Header().Set("content-type", contentType)
+	res.WriteHeader(code)
+	_, _ = res.Write(yarf.MarshalError(yarf.NewRPCError(status, msg)))
+}
+
+// streamError returns the error of a response to a request for a stream that could not be opened, which is the
+// RPCError of the yarf message responded with, or a HTTPError if there is none
+func streamError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.Header.Get("content-type") == contentType {
+		if rpcErr, ok := yarf.UnmarshalError(body); ok {
+			return rpcErr
+		}
+	}
+	return newHTTPError(resp, body)
+}
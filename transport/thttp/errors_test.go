@@ -0,0 +1,145 @@
+package thttp
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func discoveryOf(t *testing.T, server *httptest.Server) Discovery {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DiscoveryDefault{Host: host, Port: port}
+}
+
+// recorder records the http status codes of the responses received
+type recorder map[string]int
+
+func (r recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		r[req.URL.Path] = resp.StatusCode
+	}
+	return resp, err
+}
+
+func newTestPair(t *testing.T, codes recorder) (yarf.Server, yarf.Client, *httptest.Server) {
+	transport, err := NewHTTPTransporter(Options{Client: Client{HTTPClient: &http.Client{Transport: codes}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(transport.mux)
+	t.Cleanup(server.Close)
+	transport.options.Discovery = discoveryOf(t, server)
+
+	return yarf.NewServer(transport, "test"), yarf.NewClient(transport), server
+}
+
+func TestErrorStatus(t *testing.T) {
+	codes := recorder{}
+	server, client, httpServer := newTestPair(t, codes)
+	server.Handle("fail", func(request *yarf.Msg, response *yarf.Msg) error {
+		return yarf.NewRPCError(yarf.StatusHandlerError, "failed")
+	})
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+
+	err := client.Request("test.ok").Done()
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = client.Request("test.fail").Done()
+	var rpcErr yarf.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Status != yarf.StatusHandlerError || rpcErr.Msg != "failed" {
+		t.Errorf("expected the error of the handler, got %v", err)
+	}
+
+	err = client.Request("test.missing").Done()
+	if !errors.As(err, &rpcErr) || rpcErr.Status != yarf.StatusFunctionNotFound {
+		t.Errorf("expected function not found, got %v", err)
+	}
+
+	for path, code := range map[string]int{"/test.ok": 200, "/test.fail": 500, "/test.missing": 404} {
+		if codes[path] != code {
+			t.Errorf("expected http status %d of %s, got %d", code, path, codes[path])
+		}
+	}
+
+	resp, err := http.Post(httpServer.URL+"/test.ok", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || resp.Header.Get("content-type") != contentType {
+		t.Errorf("expected http status 400 with content type %s, got %d %s", contentType, resp.StatusCode, resp.Header.Get("content-type"))
+	}
+
+	resp, err = http.Get(httpServer.URL + "/test.ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected http status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>bad gateway</html>"))
+	}))
+	defer proxy.Close()
+
+	transport, err := NewHTTPTransporter(Options{Discovery: discoveryOf(t, proxy)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := yarf.NewClient(transport)
+
+	err = client.Request("test.fn").Done()
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway || string(httpErr.Body) != "<html>bad gateway</html>" {
+		t.Errorf("expected http error, got %v", err)
+	}
+
+	_, err = transport.CallStream(context.Background(), "test.fn", nil)
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected http error opening stream, got %v", err)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for status, code := range map[int]int{
+		yarf.StatusOk:               200,
+		yarf.StatusHandlerError:     500,
+		yarf.StatusInternalPanic:    500,
+		yarf.StatusUnmarshalError:   400,
+		yarf.StatusFunctionNotFound: 404,
+		yarf.StatusUnauthorized:     401,
+		yarf.StatusForbidden:        403,
+		yarf.StatusInvalidSignature: 401,
+		yarf.StatusReplayed:         409,
+		yarf.StatusTooManyRequests:  429,
+		503:                         503,
+		599:                         500,
+	} {
+		if httpStatus(status) != code {
+			t.Errorf("expected status %d to map to %d, got %d", status, code, httpStatus(status))
+		}
+	}
+}
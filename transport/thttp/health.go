@@ -0,0 +1,76 @@
+package thttp
+
+import (
+	"encoding/json"
+	"github.com/modfin/yarf"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HealthPath is the path of the http endpoint reporting the health of the servers using the transporter, for probes
+// of e.g. Kubernetes. It is shared with the function healthz of a server without namespace, which is requested using
+// POST while the health is requested using GET
+const HealthPath = "/healthz"
+
+// healthResponse is the body of a response of the health endpoint
+type healthResponse struct {
+	Status  yarf.HealthStatus
+	Servers []yarf.Health
+}
+
+// ListenHealth implements yarf.HealthTransporter, adding the health of the server in namespace to the health endpoint
+func (h *HTTPTransporter) ListenHealth(namespace string, health func() yarf.Health) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health[namespace] = health
+}
+
+// serveHealth responds to GET /healthz with status 200 if every server is serving, and 503 otherwise. The query param
+// namespace limits the response to the server of the namespace
+func (h *HTTPTransporter) serveHealth(res http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		h.serveFunction(strings.TrimPrefix(HealthPath, "/"), res, req)
+		return
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.Header().Set("Allow", "GET, HEAD")
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, filtered := req.URL.Query()["namespace"]
+
+	h.mu.Lock()
+	var checks []func() yarf.Health
+	for ns, check := range h.health {
+		if !filtered || ns == namespace[0] {
+			checks = append(checks, check)
+		}
+	}
+	h.mu.Unlock()
+
+	response := healthResponse{Status: yarf.HealthServing, Servers: []yarf.Health{}}
+	for _, check := range checks {
+		health := check()
+		if health.Status != yarf.HealthServing {
+			response.Status = yarf.HealthNotServing
+		}
+		response.Servers = append(response.Servers, health)
+	}
+	sort.Slice(response.Servers, func(i, j int) bool {
+		return response.Servers[i].Namespace < response.Servers[j].Namespace
+	})
+
+	code := http.StatusOK
+	if filtered && len(checks) == 0 {
+		code = http.StatusNotFound
+		response.Status = yarf.HealthNotServing
+	} else if response.Status != yarf.HealthServing {
+		code = http.StatusServiceUnavailable
+	}
+
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(code)
+	_ = json.NewEncoder(res).Encode(response)
+}
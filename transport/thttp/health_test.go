@@ -0,0 +1,88 @@
+package thttp
+
+import (
+	"encoding/json"
+	"github.com/modfin/yarf"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getHealth(t *testing.T, url string) (int, healthResponse) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var health healthResponse
+	err = json.NewDecoder(resp.Body).Decode(&health)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, health
+}
+
+func TestHealthz(t *testing.T) {
+	server, client, httpServer := newTestPair(t, recorder{})
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+
+	code, health := getHealth(t, httpServer.URL+HealthPath)
+	if code != http.StatusOK || health.Status != yarf.HealthServing || len(health.Servers) != 1 || health.Servers[0].Namespace != "test" {
+		t.Errorf("expected the server to be serving, got %d %+v", code, health)
+	}
+
+	code, _ = getHealth(t, httpServer.URL+HealthPath+"?namespace=other")
+	if code != http.StatusNotFound {
+		t.Errorf("expected http status 404 of an unknown namespace, got %d", code)
+	}
+
+	server.SetServingStatus(yarf.HealthNotServing)
+
+	code, health = getHealth(t, httpServer.URL+HealthPath+"?namespace=test")
+	if code != http.StatusServiceUnavailable || health.Status != yarf.HealthNotServing {
+		t.Errorf("expected the server not to be serving, got %d %+v", code, health)
+	}
+
+	h, err := client.Health("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Status != yarf.HealthNotServing || h.Functions["test.ok"] != yarf.HealthServing {
+		t.Errorf("unexpected health %+v", h)
+	}
+}
+
+func TestHealthzFunction(t *testing.T) {
+	transport, err := NewHTTPTransporter(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(transport.mux)
+	t.Cleanup(httpServer.Close)
+	transport.options.Discovery = discoveryOf(t, httpServer)
+
+	// A function at the path of the health endpoint
+	server := yarf.NewServer(transport, "")
+	server.Handle("healthz", func(request *yarf.Msg, response *yarf.Msg) error {
+		response.SetContent("function")
+		return nil
+	})
+	client := yarf.NewClient(transport)
+
+	var content string
+	err = client.Request("healthz").BindResponseContent(&content).Done()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "function" {
+		t.Errorf("expected the function to respond, got %q", content)
+	}
+
+	code, health := getHealth(t, httpServer.URL+HealthPath)
+	if code != http.StatusOK || health.Status != yarf.HealthServing {
+		t.Errorf("expected the health endpoint to respond, got %d %+v", code, health)
+	}
+}
@@ -0,0 +1,168 @@
+package thttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/modfin/yarf"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// headerStream is the http header used by the client to request a stream, with one of the values below
+const headerStream = "X-Yarf-Stream"
+
+const (
+	// streamServer requests a stream of responses to a single request
+	streamServer = "server"
+	// streamDuplex requests a bidirectional stream
+	streamDuplex = "duplex"
+)
+
+// streamContentType is the http content type of a response containing a stream of frames
+const streamContentType = "application/x-yarf-stream"
+
+// A stream is sent as a sequence of frames, each prefixed with its length as a big endian uint32. An empty frame marks
+// the end of the stream, which makes it possible to tell a stream that has ended apart from a broken connection.
+func writeFrame(w io.Writer, data []byte) error {
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	_, err := w.Write(append(header, data...))
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size == 0 {
+		return nil, io.EOF
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return data, err
+}
+
+type httpStreamReceiver struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	ended  bool
+}
+
+// CallStream implements client side call of a streaming function
+func (h *HTTPTransporter) CallStream(ctx context.Context, function string, requestData []byte) (yarf.StreamReceiver, error) {
+
+	if h.IsClose() {
+		return nil, errors.New("transport layer is has been closed")
+	}
+
+	url, err := h.options.Discovery.URL()
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url+"/"+function, bytes.NewReader(requestData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/octet-stream")
+	req.Header.Set(headerStream, streamServer)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("content-type") != streamContentType {
+		defer resp.Body.Close()
+		return nil, streamError(resp)
+	}
+
+	return &httpStreamReceiver{body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// Recv implements yarf.StreamReceiver
+func (r *httpStreamReceiver) Recv() ([]byte, error) {
+	if r.ended {
+		return nil, io.EOF
+	}
+	data, err := readFrame(r.reader)
+	if err == io.EOF && data == nil {
+		r.ended = true
+	}
+	return data, err
+}
+
+// Close implements yarf.StreamReceiver
+func (r *httpStreamReceiver) Close() error {
+	return r.body.Close()
+}
+
+// ListenStream defines the function that will handle yarf streaming requests
+func (h *HTTPTransporter) ListenStream(function string, toExec func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handle(function)
+	h.streams[function] = toExec
+	return nil
+}
+
+func (h *HTTPTransporter) serveStream(function string, res http.ResponseWriter, req *http.Request, reqData []byte) {
+
+	h.mu.Lock()
+	toExec := h.streams[function]
+	h.mu.Unlock()
+
+	if toExec == nil {
+		writeError(res, http.StatusNotFound, yarf.StatusFunctionNotFound, "no stream "+function+" is listened to")
+		return
+	}
+
+	// A stream may live longer than the server write timeout, which is intended for a single response
+	rc := http.NewResponseController(res)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	res.Header().Set("content-type", streamContentType)
+	res.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	var mu sync.Mutex
+	send := func(data []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := req.Context().Err(); err != nil {
+			return err
+		}
+		err := writeFrame(res, data)
+		if err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	respData := toExec(req.Context(), reqData, send)
+
+	err := send(respData)
+	if err != nil {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	_ = writeFrame(res, nil)
+	_ = rc.Flush()
+}
@@ -0,0 +1,167 @@
+package thttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/modfin/yarf"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// writeCert creates a certificate signed by parent, or self signed if nil, and writes it and its key to dir
+func writeCert(t *testing.T, dir string, name string, parent *testCert, template *x509.Certificate) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.Subject = pkix.Name{CommonName: name}
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	ca := writeCert(t, dir, "ca", nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	writeCert(t, dir, "server", ca, &x509.Certificate{
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	writeCert(t, dir, "client", ca, &x509.Certificate{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	serverConfig, err := ServerTLSConfig(file("server.pem"), file("server.key"), file("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.TLS = serverConfig
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	clientConfig, err := ClientTLSConfig(file("ca.pem"), file("client.pem"), file("client.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ := newHTTPClient(Client{TLSConfig: clientConfig, HTTP2: true})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+
+	client, _ = newHTTPClient(Client{TLSConfig: clientConfig})
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 1 {
+		t.Errorf("expected HTTP/1.1 unless HTTP2 is set, got %s", resp.Proto)
+	}
+
+	clientConfig, err = ClientTLSConfig(file("ca.pem"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ = newHTTPClient(Client{TLSConfig: clientConfig})
+
+	_, err = client.Get(server.URL)
+	if err == nil {
+		t.Error("expected request without client certificate to fail")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	transport, err := NewHTTPTransporter(Options{Client: Client{Timeout: 100 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(transport.mux)
+	defer httpServer.Close()
+	transport.options.Discovery = discoveryOf(t, httpServer)
+
+	server := yarf.NewServer(transport, "test")
+	server.Handle("slow", func(request *yarf.Msg, response *yarf.Msg) error {
+		time.Sleep(300 * time.Millisecond)
+		return nil
+	})
+	client := yarf.NewClient(transport)
+
+	err = client.Request("test.slow").Done()
+	if err == nil {
+		t.Error("expected request to time out")
+	}
+
+	// An explicit timeout takes precedence over the timeout of the client
+	err = client.Request("test.slow").WithTimeout(2 * time.Second).Done()
+	if err != nil {
+		t.Errorf("expected request with a longer explicit timeout to succeed, got %v", err)
+	}
+
+	// Streams use the same client, which has no timeout of its own
+	if transport.client.Timeout != 0 {
+		t.Errorf("expected client without a timeout, got %v", transport.client.Timeout)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"github.com/modfin/yarf"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -22,14 +25,20 @@ const (
 // HTTPTransporter implements the yarf.Transport for using http as a transport protocol
 type HTTPTransporter struct {
 	options   Options
-	functions map[string]func(requestData []byte) (responseData []byte)
+	functions map[string]func(ctx context.Context, requestData []byte) (responseData []byte)
+	streams   map[string]func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte)
+	duplexes  map[string]func(ctx context.Context, stream yarf.TransportStream)
+	health    map[string]func() yarf.Health
 
 	initServer sync.Once
 	server     *http.Server
 	mux        *http.ServeMux
+	client     *http.Client
+	timeout    time.Duration
 
-	mu sync.Mutex
-	closed chan struct{}
+	mu       sync.Mutex
+	closed   chan struct{}
+	listener net.Listener
 }
 
 // Options defines the options used by the http yarf transport
@@ -41,23 +50,103 @@ type Options struct {
 
 // Server defines the server config used
 type Server struct {
-	Addr      string
+	Addr string
+
+	// TLSConfig makes the server serve https, using the certificates of the config. Mutual TLS is enabled by setting
+	// ClientCAs and ClientAuth, e.g. using ServerTLSConfig
 	TLSConfig *tls.Config
-	timeout   time.Duration
+
+	// Timeout limits the time of reading a request and writing its response, defaults to 10s. Streams are not limited
+	Timeout time.Duration
 }
 
-// Client defines the server config used
+// Client defines the client config used
 type Client struct {
+	// Timeout limits the time of a request, including reading the response, unless the request has an earlier deadline
+	// or a timeout set explicitly using RPC.WithTimeout or Client.WithDefaultTimeout. Streams are only limited by the
+	// context of the request. Zero means no timeout
 	Timeout time.Duration
+
+	// MaxIdleConns, MaxIdleConnsPerHost, MaxConnsPerHost and IdleConnTimeout configures the connection pool, the
+	// defaults of http.DefaultTransport are used when zero
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+
+	// TLSConfig is used when connecting to servers using https, e.g. with a CA bundle in RootCAs and client
+	// certificates for mutual TLS in Certificates, see ClientTLSConfig
+	TLSConfig *tls.Config
+
+	// HTTP2 attempts to use HTTP/2 when connecting using https, otherwise HTTP/1.1 is used
+	HTTP2 bool
+
+	// HTTPClient is used as is if provided, ignoring the options above
+	HTTPClient *http.Client
+}
+
+// newHTTPClient creates the http client used for requests and streams, and returns the timeout of requests. The client
+// has no timeout of its own, since the timeout is applied to the context of every request, see withTimeout
+func newHTTPClient(options Client) (client *http.Client, timeout time.Duration) {
+	if options.HTTPClient != nil {
+		client = &http.Client{}
+		*client = *options.HTTPClient
+		client.Timeout = 0
+		return client, options.HTTPClient.Timeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.MaxIdleConns > 0 {
+		transport.MaxIdleConns = options.MaxIdleConns
+	}
+	if options.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	}
+	if options.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = options.MaxConnsPerHost
+	}
+	if options.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = options.IdleConnTimeout
+	}
+	if options.TLSConfig != nil {
+		// Cloned since HTTP/2 adds its protocol to the config, which would be negotiated by other clients sharing it
+		transport.TLSClientConfig = options.TLSConfig.Clone()
+	}
+	transport.ForceAttemptHTTP2 = options.HTTP2
+
+	return &http.Client{Transport: transport}, options.Timeout
+}
+
+// withTimeout applies the timeout of the client to the context of a request, unless the deadline of the context is
+// earlier, or is set explicitly using RPC.WithTimeout or Client.WithDefaultTimeout which takes precedence
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if deadline, ok := ctx.Deadline(); ok && (yarf.HasExplicitTimeout(ctx) || time.Until(deadline) < timeout) {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // NewHTTPTransporter a constructor for the HTTPTransporter
 func NewHTTPTransporter(options Options) (*HTTPTransporter, error) {
 	t := HTTPTransporter{
-		options: options,
-		mux:     http.NewServeMux(),
-		closed:  make(chan struct{}),
+		options:   options,
+		functions: map[string]func(ctx context.Context, requestData []byte) (responseData []byte){},
+		streams:   map[string]func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte){},
+		duplexes:  map[string]func(ctx context.Context, stream yarf.TransportStream){},
+		health:    map[string]func() yarf.Health{},
+		mux:       http.NewServeMux(),
+		closed:    make(chan struct{}),
 	}
+	t.client, t.timeout = newHTTPClient(options.Client)
+
+	// Requests to functions that are not listened to are responded with an error the client understands
+	t.mux.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		writeError(res, http.StatusNotFound, yarf.StatusFunctionNotFound, "no function "+strings.TrimPrefix(req.URL.Path, "/")+" is listened to")
+	})
+	t.mux.HandleFunc(HealthPath, t.serveHealth)
 
 	return &t, nil
 }
@@ -75,14 +164,19 @@ func (h *HTTPTransporter) Call(ctx context.Context, function string, requestData
 		return nil, err
 	}
 
+	ctx, cancel := withTimeout(ctx, h.timeout)
+	defer cancel()
+
 	r := bytes.NewReader(requestData)
 
-	req, err := http.NewRequest("POST", url+"/"+function, r)
-	req = req.WithContext(ctx)
-	req.Header.Set("WithContent-Type", "application/octet-stream")
+	req, err := http.NewRequestWithContext(ctx, "POST", url+"/"+function, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/octet-stream")
 
 	var resp *http.Response
-	resp, err = http.DefaultClient.Do(req)
+	resp, err = h.client.Do(req)
 
 	if err != nil {
 		return nil, err
@@ -90,28 +184,127 @@ func (h *HTTPTransporter) Call(ctx context.Context, function string, requestData
 
 	defer func() { resp.Body.Close() }()
 
-	return ioutil.ReadAll(resp.Body)
+	response, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are responded with a yarf message as well, and are returned to the caller by yarf. Anything else, e.g. the
+	// error page of a proxy, is a HTTPError. Servers of earlier versions does not set the content type, why the message
+	// is recognized by its content if missing
+	if resp.Header.Get("content-type") != contentType {
+		if _, err = yarf.UnmarshalStatus(response); err != nil {
+			return nil, newHTTPError(resp, response)
+		}
+	}
+
+	return response, nil
 }
 
-// Start initiates the http server to receive requests
+// Handler returns the http.Handler serving the yarf functions, which makes it possible to mount yarf into an existing
+// http server, e.g. next to health and metrics endpoints. Requests are routed on the path /<function>
+func (h *HTTPTransporter) Handler() http.Handler {
+	return h.mux
+}
+
+// httpServer returns the http server used by Start and Serve, creating it on first use
+func (h *HTTPTransporter) httpServer() *http.Server {
+	h.initServer.Do(func() {
+		server := &http.Server{
+			Addr:           stringOr(h.options.Server.Addr, ":"+StdPort),
+			TLSConfig:      h.options.Server.TLSConfig,
+			Handler:        h.mux,
+			ReadTimeout:    durationOr(h.options.Server.Timeout, 10*time.Second),
+			WriteTimeout:   durationOr(h.options.Server.Timeout, 10*time.Second),
+			MaxHeaderBytes: 1 << 20,
+		}
+
+		h.mu.Lock()
+		h.server = server
+		h.mu.Unlock()
+	})
+	return h.server
+}
+
+// listen opens the listener of the configured address
+func (h *HTTPTransporter) listen() (net.Listener, error) {
+	if h.IsClose() {
+		return nil, http.ErrServerClosed
+	}
+	return net.Listen("tcp", h.httpServer().Addr)
+}
+
+// Start initiates the http server to receive requests, and blocks until it is closed
 func (h *HTTPTransporter) Start() error {
+	l, err := h.listen()
+	if err != nil {
+		return err
+	}
+	return h.Serve(l)
+}
 
-	h.server = &http.Server{
-		Addr:           stringOr(h.options.Server.Addr, ":"+StdPort),
-		TLSConfig:      h.options.Server.TLSConfig,
-		Handler:        h.mux,
-		ReadTimeout:    durationOr(h.options.Server.timeout, 10*time.Second),
-		WriteTimeout:   durationOr(h.options.Server.timeout, 10*time.Second),
-		MaxHeaderBytes: 1 << 20,
+// StartAsync initiates the http server to receive requests without blocking. An error is returned if the server is
+// not able to listen on the configured address, otherwise the server is ready to receive requests once it returns.
+func (h *HTTPTransporter) StartAsync() error {
+	l, err := h.listen()
+	if err != nil {
+		return err
 	}
 
-	return h.server.ListenAndServe()
+	// The listener is recorded up front, making Addr available once StartAsync returns
+	h.mu.Lock()
+	h.listener = l
+	h.mu.Unlock()
+
+	go func() {
+		err := h.Serve(l)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			yarf.Logger().Error("yarf: http server stopped", "addr", l.Addr().String(), "err", err)
+		}
+	}()
+	return nil
 }
 
+// Serve receives requests on the listener, and blocks until the server is closed, e.g. for serving on a listener of
+// port 0 in tests or one provided by socket activation. It may be called with multiple listeners
+func (h *HTTPTransporter) Serve(l net.Listener) error {
+	server := h.httpServer()
 
+	h.mu.Lock()
+	if h.isClosed() {
+		h.mu.Unlock()
+		_ = l.Close()
+		return http.ErrServerClosed
+	}
+	h.listener = l
+	h.mu.Unlock()
+
+	if server.TLSConfig != nil {
+		return server.ServeTLS(l, "", "")
+	}
+	return server.Serve(l)
+}
+
+// Addr returns the address of the last listener served on, which is useful when listening on port 0, or nil if the
+// server has not been started
+func (h *HTTPTransporter) Addr() net.Addr {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.listener == nil {
+		return nil
+	}
+	return h.listener.Addr()
+}
+
+// IsClose returns true if the transporter has been closed
 func (h *HTTPTransporter) IsClose() bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	return h.isClosed()
+}
+
+// isClosed must be called while holding the lock
+func (h *HTTPTransporter) isClosed() bool {
 	select {
 	case <-h.closed:
 		return true
@@ -119,69 +312,107 @@ func (h *HTTPTransporter) IsClose() bool {
 		return false
 	}
 }
+
 // Close halts the http server to receive requests
 func (h *HTTPTransporter) Close() error {
-	h.mu.Lock()
-	select {
-	case <-h.closed:
-	default:
-		close(h.closed)
-	}
-	h.mu.Unlock()
-	return h.server.Shutdown(context.Background())
+	return h.shutdown(context.Background())
 }
+
+// CloseGraceful halts the http server to receive requests, waiting up to timeout for requests in flight to finish
 func (h *HTTPTransporter) CloseGraceful(timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	return h.shutdown(ctx)
+}
+
+// shutdown closes the transporter, and the http server if it has been started. A client only transporter has no server
+func (h *HTTPTransporter) shutdown(ctx context.Context) error {
 	h.mu.Lock()
-	select {
-	case <-h.closed:
-	default:
+	if !h.isClosed() {
 		close(h.closed)
 	}
+	server := h.server
 	h.mu.Unlock()
-	return h.server.Shutdown(ctx)
-}
 
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
 
 // Listen defines the function that will handle yarf requests
 func (h *HTTPTransporter) Listen(function string, toExec func(ctx context.Context, requestData []byte) (responseData []byte)) (err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handle(function)
+	h.functions[function] = toExec
+	return
+}
+
+// handle registers the function on the mux, unless it already has been, and must be called while holding the lock
+func (h *HTTPTransporter) handle(function string) {
+	if h.functions[function] != nil || h.streams[function] != nil || h.duplexes[function] != nil {
+		return
+	}
+
+	// The health endpoint is served at the path of the function, and passes the requests of yarf on to it
+	if "/"+function == HealthPath {
+		return
+	}
 
 	h.mux.HandleFunc("/"+function, func(res http.ResponseWriter, req *http.Request) {
-		defer req.Body.Close()
+		h.serveFunction(function, res, req)
+	})
+}
 
-		if req.Method != "POST" {
-			//FIX returning error
-			res.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		reqData, err := ioutil.ReadAll(req.Body)
+// serveFunction serves the requests of yarf to the function, whether a request, a stream or a duplex stream
+func (h *HTTPTransporter) serveFunction(function string, res http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
 
-		if err != nil {
-			//FIX returning error
-			res.WriteHeader(http.StatusInsufficientStorage)
-			return
-		}
+	if req.Method != "POST" {
+		res.Header().Set("Allow", "POST")
+		writeError(res, http.StatusMethodNotAllowed, yarf.StatusUnmarshalError, "method "+req.Method+" is not allowed, yarf requests are sent using POST")
+		return
+	}
 
-		if err != nil {
-			//FIX returning error
-			res.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+	// The request body of a duplex stream is read while the response is written, and is not read up front
+	if req.Header.Get(headerStream) == streamDuplex {
+		h.serveDuplex(function, res, req)
+		return
+	}
 
-		//fmt.Println("CTX", req.Context())
-		respData := toExec(req.Context(), reqData)
+	reqData, err := ioutil.ReadAll(req.Body)
 
-		res.WriteHeader(http.StatusOK)
-		res.Header().Set("content-type", "application/octet-stream")
-		_, err = res.Write(respData)
+	if err != nil {
+		writeError(res, http.StatusBadRequest, yarf.StatusUnmarshalError, "could not read request, "+err.Error())
+		return
+	}
 
-		if err != nil {
-			//FIX returning error
-			return
-		}
+	if req.Header.Get(headerStream) == streamServer {
+		h.serveStream(function, res, req, reqData)
+		return
+	}
 
-	})
+	h.mu.Lock()
+	toExec := h.functions[function]
+	h.mu.Unlock()
 
-	return
+	if toExec == nil {
+		writeError(res, http.StatusNotFound, yarf.StatusFunctionNotFound, "no function "+function+" is listened to")
+		return
+	}
+
+	respData := toExec(req.Context(), reqData)
+
+	// Only the headers of the response are decoded, in order to pick the http status code
+	code := http.StatusOK
+	if status, err := yarf.UnmarshalStatus(respData); err == nil {
+		code = httpStatus(status)
+	}
+
+	// Headers must be set before the status code is written, or they are never sent
+	res.Header().Set("content-type", contentType)
+	res.WriteHeader(code)
+	_, _ = res.Write(respData)
 }
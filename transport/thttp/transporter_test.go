@@ -0,0 +1,135 @@
+package thttp
+
+import (
+	"errors"
+	"github.com/modfin/yarf"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCloseWithoutStart(t *testing.T) {
+	transport, err := NewHTTPTransporter(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := transport.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := transport.CloseGraceful(time.Second); err != nil {
+		t.Error(err)
+	}
+	if err := transport.StartAsync(); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected a closed transporter not to start, got %v", err)
+	}
+}
+
+func TestStartAsync(t *testing.T) {
+	transport, err := NewHTTPTransporter(Options{Server: Server{Addr: "127.0.0.1:0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := yarf.NewServer(transport, "test")
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error {
+		response.SetParam("ok", true)
+		return nil
+	})
+
+	err = transport.StartAsync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+
+	addr, ok := transport.Addr().(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("expected the address listened on, got %v", transport.Addr())
+	}
+
+	clientTransport, _ := NewHTTPTransporter(Options{Discovery: &DiscoveryDefault{Host: "127.0.0.1", Port: strconv.Itoa(addr.Port)}})
+	client := yarf.NewClient(clientTransport)
+	defer client.Close()
+
+	msg, err := client.Request("test.ok").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Param("ok").BoolOr(false) {
+		t.Error("expected response of the handler")
+	}
+
+	taken, _ := NewHTTPTransporter(Options{Server: Server{Addr: transport.Addr().String()}})
+	if taken.StartAsync() == nil {
+		taken.Close()
+		t.Error("expected listening on an address in use to fail")
+	}
+}
+
+func TestServe(t *testing.T) {
+	transport, err := NewHTTPTransporter(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- transport.Serve(l) }()
+
+	resp, err := http.Post("http://"+l.Addr().String()+"/test.missing", "application/octet-stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected http status 404, got %d", resp.StatusCode)
+	}
+
+	if err := transport.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("expected Serve to return once closed, got %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	transport, err := NewHTTPTransporter(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := yarf.NewServer(transport, "test")
+	server.Handle("ok", func(request *yarf.Msg, response *yarf.Msg) error {
+		return nil
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/", transport.Handler())
+
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+
+	clientTransport, _ := NewHTTPTransporter(Options{Discovery: discoveryOf(t, httpServer)})
+	client := yarf.NewClient(clientTransport)
+
+	if err := client.Request("test.ok").Done(); err != nil {
+		t.Error(err)
+	}
+
+	resp, err := http.Get(httpServer.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected http status 200 of other endpoints, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,155 @@
+package tmem
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"io"
+	"sync"
+	"time"
+)
+
+// memPipe is one direction of a duplex stream, the frames channel is unbuffered which gives back-pressure to the sender
+type memPipe struct {
+	frames chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newMemPipe() *memPipe {
+	return &memPipe{
+		frames: make(chan []byte),
+		closed: make(chan struct{}),
+	}
+}
+
+func (p *memPipe) close() {
+	p.once.Do(func() { close(p.closed) })
+}
+
+type memDuplexStream struct {
+	ctx    context.Context
+	cancel func()
+	in     *memPipe
+	out    *memPipe
+}
+
+// CallDuplex implements client side call of a bidirectional streaming function
+func (m *MemTransporter) CallDuplex(ctx context.Context, function string) (yarf.TransportStream, error) {
+
+	m.mu.Lock()
+	select {
+	case <-m.closed:
+		m.mu.Unlock()
+		return nil, errors.New("transport layer has been closed")
+	default:
+	}
+	toExec, ok := m.duplexes[function]
+	m.count += 1
+	m.mu.Unlock()
+
+	if !ok {
+		m.decCount()
+		return nil, errors.New("no handler is listening to function " + function)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-m.kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if m.options.Latency > 0 {
+		select {
+		case <-time.After(m.options.Latency):
+		case <-ctx.Done():
+			cancel()
+			m.decCount()
+			return nil, ctx.Err()
+		}
+	}
+
+	if m.options.Failure != nil {
+		err := m.options.Failure(function)
+		if err != nil {
+			cancel()
+			m.decCount()
+			return nil, err
+		}
+	}
+
+	toServer := newMemPipe()
+	toClient := newMemPipe()
+
+	server := &memDuplexStream{ctx: ctx, cancel: cancel, in: toServer, out: toClient}
+	client := &memDuplexStream{ctx: ctx, cancel: cancel, in: toClient, out: toServer}
+
+	go func() {
+		defer m.decCount()
+		defer toClient.close()
+		toExec(ctx, server)
+	}()
+
+	return client, nil
+}
+
+// ListenDuplex defines the function that will handle yarf bidirectional streaming requests
+func (m *MemTransporter) ListenDuplex(function string, toExec func(ctx context.Context, stream yarf.TransportStream)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.closed:
+		return errors.New("transport layer has been closed")
+	default:
+	}
+
+	m.duplexes[function] = toExec
+	return nil
+}
+
+// Send implements yarf.TransportStream
+func (s *memDuplexStream) Send(data []byte) error {
+	select {
+	case <-s.out.closed:
+		return errors.New("stream has been closed for sending")
+	default:
+	}
+
+	select {
+	case s.out.frames <- append([]byte(nil), data...):
+		return nil
+	case <-s.out.closed:
+		return errors.New("stream has been closed for sending")
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Recv implements yarf.TransportStream
+func (s *memDuplexStream) Recv() ([]byte, error) {
+	select {
+	case data := <-s.in.frames:
+		return data, nil
+	case <-s.in.closed:
+		return nil, io.EOF
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// CloseSend implements yarf.TransportStream
+func (s *memDuplexStream) CloseSend() error {
+	s.out.close()
+	return nil
+}
+
+// Close implements yarf.TransportStream
+func (s *memDuplexStream) Close() error {
+	s.cancel()
+	return nil
+}
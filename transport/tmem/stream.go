@@ -0,0 +1,126 @@
+package tmem
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"io"
+	"sync"
+	"time"
+)
+
+type memStreamReceiver struct {
+	ctx    context.Context
+	cancel func()
+	frames chan []byte
+	once   sync.Once
+}
+
+// CallStream implements client side call of a streaming function
+func (m *MemTransporter) CallStream(ctx context.Context, function string, requestData []byte) (yarf.StreamReceiver, error) {
+
+	m.mu.Lock()
+	select {
+	case <-m.closed:
+		m.mu.Unlock()
+		return nil, errors.New("transport layer has been closed")
+	default:
+	}
+	toExec, ok := m.streams[function]
+	m.count += 1
+	m.mu.Unlock()
+
+	if !ok {
+		m.decCount()
+		return nil, errors.New("no handler is listening to function " + function)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-m.kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if m.options.Latency > 0 {
+		select {
+		case <-time.After(m.options.Latency):
+		case <-ctx.Done():
+			cancel()
+			m.decCount()
+			return nil, ctx.Err()
+		}
+	}
+
+	if m.options.Failure != nil {
+		err := m.options.Failure(function)
+		if err != nil {
+			cancel()
+			m.decCount()
+			return nil, err
+		}
+	}
+
+	requestData = append([]byte(nil), requestData...)
+
+	r := &memStreamReceiver{
+		ctx:    ctx,
+		cancel: cancel,
+		frames: make(chan []byte),
+	}
+
+	send := func(data []byte) error {
+		select {
+		case r.frames <- append([]byte(nil), data...):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	go func() {
+		defer m.decCount()
+		defer close(r.frames)
+		responseData := toExec(ctx, requestData, send)
+		_ = send(responseData)
+	}()
+
+	return r, nil
+}
+
+// ListenStream defines the function that will handle yarf streaming requests
+func (m *MemTransporter) ListenStream(function string, toExec func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.closed:
+		return errors.New("transport layer has been closed")
+	default:
+	}
+
+	m.streams[function] = toExec
+	return nil
+}
+
+// Recv implements yarf.StreamReceiver
+func (r *memStreamReceiver) Recv() ([]byte, error) {
+	select {
+	case data, ok := <-r.frames:
+		if !ok {
+			return nil, io.EOF
+		}
+		return data, nil
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+}
+
+// Close implements yarf.StreamReceiver
+func (r *memStreamReceiver) Close() error {
+	r.once.Do(r.cancel)
+	return nil
+}
@@ -0,0 +1,197 @@
+package tmem
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"sync"
+	"time"
+)
+
+// Options defines the options used by the in memory yarf transport
+type Options struct {
+	// Latency is injected before every call reaches its handler
+	Latency time.Duration
+
+	// Failure is called before every call and if it returns an error the call fails with it, without reaching the handler
+	Failure func(function string) error
+}
+
+// MemTransporter implements the yarf.Transport by sending calls straight to handlers registered in the same process
+type MemTransporter struct {
+	options   Options
+	functions map[string]func(ctx context.Context, requestData []byte) (responseData []byte)
+	streams   map[string]func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte)
+	duplexes  map[string]func(ctx context.Context, stream yarf.TransportStream)
+
+	mu     sync.Mutex
+	count  int64
+	idle   chan struct{}
+	closed chan struct{}
+
+	// kill is closed when in flight calls shall be canceled, i.e. on Close or when CloseGraceful times out
+	kill chan struct{}
+}
+
+// NewMemTransporter a constructor for the MemTransporter
+func NewMemTransporter(options Options) (*MemTransporter, error) {
+	t := MemTransporter{
+		options:   options,
+		functions: map[string]func(ctx context.Context, requestData []byte) (responseData []byte){},
+		streams:   map[string]func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte){},
+		duplexes:  map[string]func(ctx context.Context, stream yarf.TransportStream){},
+		closed:    make(chan struct{}),
+		kill:      make(chan struct{}),
+	}
+	return &t, nil
+}
+
+// IsClose returns true if the transporter has been closed
+func (m *MemTransporter) IsClose() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Call implements client side call of transporter
+func (m *MemTransporter) Call(ctx context.Context, function string, requestData []byte) (response []byte, err error) {
+
+	m.mu.Lock()
+	select {
+	case <-m.closed:
+		m.mu.Unlock()
+		return nil, errors.New("transport layer has been closed")
+	default:
+	}
+	toExec, ok := m.functions[function]
+	m.count += 1
+	m.mu.Unlock()
+	defer m.decCount()
+
+	if !ok {
+		return nil, errors.New("no handler is listening to function " + function)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-m.kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if m.options.Latency > 0 {
+		select {
+		case <-time.After(m.options.Latency):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if m.options.Failure != nil {
+		err = m.options.Failure(function)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The request is copied so that neither side is able to alter the others data, as they would over a network
+	requestData = append([]byte(nil), requestData...)
+
+	result := make(chan []byte, 1)
+	go func() {
+		result <- toExec(ctx, requestData)
+	}()
+
+	select {
+	case response = <-result:
+		return response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Listen defines the function that will handle yarf requests
+func (m *MemTransporter) Listen(function string, toExec func(ctx context.Context, requestData []byte) (responseData []byte)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.closed:
+		return errors.New("transport layer has been closed")
+	default:
+	}
+
+	m.functions[function] = toExec
+	return nil
+}
+
+// Close halts the transporter and cancels all calls in flight
+func (m *MemTransporter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeChannels(true)
+	return nil
+}
+
+// CloseGraceful halts the transporter from receiving new calls and waits for the ones in flight to finish, or until
+// the timeout has passed at which point they are canceled
+func (m *MemTransporter) CloseGraceful(timeout time.Duration) error {
+	m.mu.Lock()
+	m.closeChannels(false)
+	if m.count == 0 {
+		m.closeChannels(true)
+		m.mu.Unlock()
+		return nil
+	}
+	if m.idle == nil {
+		m.idle = make(chan struct{})
+	}
+	idle := m.idle
+	m.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-time.After(timeout):
+	}
+
+	m.mu.Lock()
+	m.closeChannels(true)
+	m.mu.Unlock()
+	return nil
+}
+
+// closeChannels must be called while holding the lock
+func (m *MemTransporter) closeChannels(kill bool) {
+	select {
+	case <-m.closed:
+	default:
+		close(m.closed)
+	}
+	if !kill {
+		return
+	}
+	select {
+	case <-m.kill:
+	default:
+		close(m.kill)
+	}
+}
+
+func (m *MemTransporter) decCount() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.count -= 1
+	if m.count == 0 && m.idle != nil {
+		close(m.idle)
+		m.idle = nil
+	}
+}
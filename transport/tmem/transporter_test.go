@@ -0,0 +1,277 @@
+package tmem
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"io"
+	"testing"
+	"time"
+)
+
+func newPair(t *testing.T, options Options) (*MemTransporter, yarf.Client) {
+	transport, err := NewMemTransporter(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := yarf.NewServer(transport, "a", "mem")
+	server.Handle("add", func(req *yarf.Msg, resp *yarf.Msg) error {
+		resp.SetParam("res", req.Param("val1").IntOr(0)+req.Param("val2").IntOr(0))
+		return nil
+	})
+	server.Handle("sleep", func(req *yarf.Msg, resp *yarf.Msg) error {
+		select {
+		case <-time.After(time.Duration(req.Param("sleep").IntOr(0)) * time.Millisecond):
+		case <-req.Context().Done():
+			return req.Context().Err()
+		}
+		resp.SetParam("res", req.Param("sleep").IntOr(0))
+		return nil
+	})
+
+	return transport, yarf.NewClient(transport)
+}
+
+func TestCall(t *testing.T) {
+	_, client := newPair(t, Options{})
+
+	msg, err := client.Request("a.mem.add").WithParam("val1", 5).WithParam("val2", 7).Get()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if msg.Param("res").IntOr(0) != 12 {
+		t.Fatal("expected res to be 12, got", msg.Param("res").Value())
+	}
+}
+
+func TestCallUnknownFunction(t *testing.T) {
+	_, client := newPair(t, Options{})
+
+	_, err := client.Request("a.mem.nope").Get()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	_, client := newPair(t, Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Request("a.mem.sleep").WithParam("sleep", 1000).WithContext(ctx).Get()
+	if err != context.DeadlineExceeded {
+		t.Fatal("expected context deadline exceeded, got", err)
+	}
+}
+
+func TestInjectedLatency(t *testing.T) {
+	_, client := newPair(t, Options{Latency: 100 * time.Millisecond})
+
+	start := time.Now()
+	err := client.Request("a.mem.add").Done()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatal("expected latency to be injected")
+	}
+}
+
+func TestInjectedFailure(t *testing.T) {
+	injected := errors.New("injected")
+	_, client := newPair(t, Options{Failure: func(function string) error {
+		if function == "a.mem.add" {
+			return injected
+		}
+		return nil
+	}})
+
+	err := client.Request("a.mem.add").Done()
+	if err != injected {
+		t.Fatal("expected injected error, got", err)
+	}
+
+	err = client.Request("a.mem.sleep").Done()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	transport, client := newPair(t, Options{})
+
+	transit := client.Request("a.mem.sleep").WithParam("sleep", 1000).Async()
+	time.Sleep(50 * time.Millisecond)
+
+	err := transport.Close()
+	if err != nil {
+		t.Fatal("expected no error closing, got", err)
+	}
+
+	_, err = transit.Get()
+	if err != context.Canceled {
+		t.Fatal("expected context canceled, got", err)
+	}
+
+	_, err = client.Request("a.mem.add").Get()
+	if err == nil {
+		t.Fatal("expected an error calling a closed transporter")
+	}
+}
+
+func TestCloseGraceful(t *testing.T) {
+	transport, client := newPair(t, Options{})
+
+	transit := client.Request("a.mem.sleep").WithParam("sleep", 200).Async()
+	time.Sleep(50 * time.Millisecond)
+
+	err := transport.CloseGraceful(time.Second)
+	if err != nil {
+		t.Fatal("expected no error closing, got", err)
+	}
+
+	msg, err := transit.Get()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if msg.Param("res").IntOr(0) != 200 {
+		t.Fatal("expected res to be 200, got", msg.Param("res").Value())
+	}
+}
+
+func TestCloseGracefulTimeout(t *testing.T) {
+	transport, client := newPair(t, Options{})
+
+	transit := client.Request("a.mem.sleep").WithParam("sleep", 1000).Async()
+	time.Sleep(50 * time.Millisecond)
+
+	err := transport.CloseGraceful(50 * time.Millisecond)
+	if err != nil {
+		t.Fatal("expected no error closing, got", err)
+	}
+
+	_, err = transit.Get()
+	if err != context.Canceled {
+		t.Fatal("expected context canceled, got", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	transport, client := newPair(t, Options{})
+
+	server := yarf.NewServer(transport, "a", "mem")
+	server.Handle("count", func(req *yarf.Msg, resp *yarf.Msg) error {
+		for i := 0; i < 3; i++ {
+			err := resp.Send(new(yarf.Msg).SetParam("i", i))
+			if err != nil {
+				return err
+			}
+		}
+		resp.SetParam("res", 3)
+		return nil
+	})
+
+	rpc := client.Request("a.mem.count")
+	stream := rpc.Stream()
+
+	var i int64
+	for stream.Next() {
+		if stream.Msg().Param("i").IntOr(-1) != i {
+			t.Fatal("expected message", i, "got", stream.Msg().Param("i").Value())
+		}
+		i++
+	}
+	if stream.Err() != nil {
+		t.Fatal("expected no error, got", stream.Err())
+	}
+	if i != 3 {
+		t.Fatal("expected 3 messages, got", i)
+	}
+
+	msg, err := rpc.Get()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if msg.Param("res").IntOr(0) != 3 {
+		t.Fatal("expected res to be 3, got", msg.Param("res").Value())
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	transport, client := newPair(t, Options{})
+
+	canceled := make(chan struct{})
+	server := yarf.NewServer(transport, "a", "mem")
+	server.Handle("endless", func(req *yarf.Msg, resp *yarf.Msg) error {
+		for {
+			err := resp.Send(new(yarf.Msg))
+			if err != nil {
+				close(canceled)
+				return err
+			}
+		}
+	})
+
+	stream := client.Request("a.mem.endless").Stream()
+	stream.Next()
+	stream.Close()
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the handler to be canceled")
+	}
+}
+
+func TestDuplex(t *testing.T) {
+	transport, client := newPair(t, Options{})
+
+	server := yarf.NewServer(transport, "a", "mem")
+	server.HandleStream("echo", func(stream *yarf.Stream) error {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = stream.Send(msg)
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	stream, err := client.Stream("a.mem.echo")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer stream.Close()
+
+	for i := 0; i < 3; i++ {
+		err = stream.Send(new(yarf.Msg).SetParam("i", i))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if msg.Param("i").IntOr(-1) != int64(i) {
+			t.Fatal("expected", i, "got", msg.Param("i").Value())
+		}
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = stream.Recv()
+	if err != io.EOF {
+		t.Fatal("expected io.EOF, got", err)
+	}
+}
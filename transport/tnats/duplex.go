@@ -0,0 +1,192 @@
+package tnats
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"io"
+	"strings"
+	"sync"
+)
+
+// duplexPrefix is prepended to the function subject of bidirectional streaming functions
+const duplexPrefix = "_duplex."
+
+// natsDuplexStream is a bidirectional stream where each side subscribes to an inbox of its own and sends frames to the
+// inbox of the other side. It is used on both the client and server side.
+type natsDuplexStream struct {
+	transporter *NatsTransporter
+	ctx         context.Context
+	cancel      func()
+
+	sub   *nats.Subscription
+	peer  string
+	ended bool
+
+	sendMu sync.Mutex
+	sent   bool
+
+	once sync.Once
+}
+
+// CallDuplex implements client side call of a bidirectional streaming function. The transporter timeout is applied as
+// the longest time allowed between two messages of the stream.
+func (n *NatsTransporter) CallDuplex(ctx context.Context, function string) (yarf.TransportStream, error) {
+	if n.IsClose() {
+		return nil, errors.New("transport layer is has been closed")
+	}
+
+	if n.client.IsClosed() {
+		return nil, errors.New("nats transporter has been closed")
+	}
+
+	inbox := streamInboxPrefix + nuid.Next()
+	sub, err := n.client.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	n.incCount()
+	ctx, cancel := context.WithCancel(ctx)
+
+	com := n.fromFunction(n.namespace + duplexPrefix + function)
+
+	go func() {
+		<-ctx.Done()
+		n.client.Publish(com.ctrl, []byte(ctrlCancel))
+	}()
+
+	s := &natsDuplexStream{
+		transporter: n,
+		ctx:         ctx,
+		cancel:      cancel,
+		sub:         sub,
+	}
+
+	// The server replies with the inbox it receives the stream on
+	openCtx, openCancel := context.WithTimeout(ctx, n.timeout)
+	defer openCancel()
+
+	msg, err := n.client.RequestWithContext(openCtx, com.function, []byte(com.ctrl+inbox))
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.peer = string(msg.Data)
+
+	return s, nil
+}
+
+// ListenDuplex defines the function that will handle yarf bidirectional streaming requests
+func (n *NatsTransporter) ListenDuplex(function string, toExec func(ctx context.Context, stream yarf.TransportStream)) error {
+
+	if n.IsClose() {
+		return errors.New("transport layer is has been closed")
+	}
+
+	queueGroup := function
+	parts := strings.Split(function, ".")
+	if len(parts) > 1 {
+		serverNamespaces := parts[:len(parts)-1]
+		queueGroup = strings.Join(serverNamespaces, ".")
+	}
+
+	function = n.namespace + duplexPrefix + function
+	queueGroup = n.namespace + queueGroup
+
+	sub, err0 := n.client.QueueSubscribe(function, queueGroup, func(m *nats.Msg) {
+		go func() {
+			n.incCount()
+			defer n.decCount()
+			com := n.fromMessage(m)
+
+			ctx, cancel := com.contextCanceler()
+			defer cancel()
+
+			inbox := streamInboxPrefix + nuid.Next()
+			sub, err := n.client.SubscribeSync(inbox)
+			if err != nil {
+				n.log().Error("could not subscribe to stream inbox", "function", function, "error", err)
+				return
+			}
+			defer sub.Unsubscribe()
+
+			err = m.Respond([]byte(inbox))
+			if err != nil {
+				n.log().Error("could not open stream", "function", function, "error", err)
+				return
+			}
+
+			s := &natsDuplexStream{
+				transporter: n,
+				ctx:         ctx,
+				cancel:      cancel,
+				sub:         sub,
+				peer:        string(m.Data),
+			}
+
+			toExec(ctx, s)
+
+			err = s.CloseSend()
+			if err != nil {
+				n.log().Error("could not close stream", "function", function, "error", err)
+				return
+			}
+		}()
+	})
+
+	n.mu.Lock()
+	n.subs = append(n.subs, sub)
+	n.mu.Unlock()
+
+	return err0
+}
+
+// Send implements yarf.TransportStream
+func (s *natsDuplexStream) Send(data []byte) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.sent {
+		return errors.New("stream has been closed for sending")
+	}
+	return s.transporter.sendFrames(s.ctx, s.peer, data)
+}
+
+// Recv implements yarf.TransportStream
+func (s *natsDuplexStream) Recv() ([]byte, error) {
+	if s.ended {
+		return nil, io.EOF
+	}
+
+	data, err := s.transporter.receiveFrames(s.ctx, s.sub)
+	if err == io.EOF {
+		s.ended = true
+	}
+	return data, err
+}
+
+// CloseSend implements yarf.TransportStream
+func (s *natsDuplexStream) CloseSend() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.sent {
+		return nil
+	}
+	s.sent = true
+	return s.transporter.client.Publish(s.peer, []byte{frameEnd})
+}
+
+// Close implements yarf.TransportStream
+func (s *natsDuplexStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		err = s.sub.Unsubscribe()
+		s.transporter.decCount()
+	})
+	return err
+}
@@ -1,6 +1,24 @@
 package tnats
 
-import "encoding/binary"
+import (
+	"context"
+	"encoding/binary"
+	"github.com/modfin/yarf"
+	"time"
+)
+
+// callTimeout returns the timeout of a call, which is the timeout of the transporter unless the deadline of ctx is
+// earlier, or is set explicitly using RPC.WithTimeout or Client.WithDefaultTimeout
+func callTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+	if remaining := time.Until(deadline); yarf.HasExplicitTimeout(ctx) || remaining < timeout {
+		return remaining
+	}
+	return timeout
+}
 
 func intToBytes(i int) []byte {
 	bytes := make([]byte, 4)
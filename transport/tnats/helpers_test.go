@@ -1,7 +1,11 @@
 package tnats
 
 import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
 	"testing"
+	"time"
 )
 
 func TestMin(t *testing.T) {
@@ -29,3 +33,48 @@ func TestIntToBytes(t *testing.T) {
 	}
 
 }
+
+// contextTransporter records the context of the last call
+type contextTransporter struct {
+	ctx context.Context
+}
+
+func (c *contextTransporter) Call(ctx context.Context, function string, requestData []byte) ([]byte, error) {
+	c.ctx = ctx
+	return nil, errors.New("not sent")
+}
+func (c *contextTransporter) Listen(function string, toExec func(ctx context.Context, requestData []byte) (responseData []byte)) error {
+	return nil
+}
+func (c *contextTransporter) Close() error                              { return nil }
+func (c *contextTransporter) CloseGraceful(timeout time.Duration) error { return nil }
+
+func TestCallTimeout(t *testing.T) {
+	within := func(timeout time.Duration, expected time.Duration) bool {
+		return timeout <= expected && timeout > expected-time.Second
+	}
+
+	if timeout := callTimeout(context.Background(), 10*time.Second); timeout != 10*time.Second {
+		t.Errorf("expected the transporter timeout without a deadline, got %v", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if timeout := callTimeout(ctx, 10*time.Second); timeout != 10*time.Second {
+		t.Errorf("expected the transporter timeout before a later deadline, got %v", timeout)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if timeout := callTimeout(ctx, 10*time.Second); !within(timeout, 5*time.Second) {
+		t.Errorf("expected an earlier deadline to be used, got %v", timeout)
+	}
+
+	// A timeout set explicitly takes precedence, even when longer than the transporter timeout
+	transport := &contextTransporter{}
+	client := yarf.NewClient(transport)
+	_ = client.Request("fn").WithTimeout(time.Minute).Done()
+	if timeout := callTimeout(transport.ctx, 10*time.Second); !within(timeout, time.Minute) {
+		t.Errorf("expected an explicit timeout to be used, got %v", timeout)
+	}
+}
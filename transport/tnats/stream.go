@@ -0,0 +1,257 @@
+package tnats
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nuid"
+	"io"
+	"strings"
+	"sync"
+)
+
+// streamPrefix is prepended to the function subject of streaming functions
+const streamPrefix = "_stream."
+
+// streamInboxPrefix is the prefix of the subject a client receives a stream on, the full subject has the same length
+// as the ctrl header
+const streamInboxPrefix = "_Y_STRM."
+
+// Frames of a stream are prefixed with one of the following types. A message larger than the max payload is split into
+// partial frames followed by a data frame. Every partial and data frame is acknowledged by the receiver, which gives
+// back-pressure to the sender.
+const (
+	frameData    byte = 'D'
+	framePartial byte = 'P'
+	frameEnd     byte = 'E'
+)
+
+var ack = []byte("OK")
+
+type natsStreamReceiver struct {
+	transporter *NatsTransporter
+	ctx         context.Context
+	cancel      func()
+	sub         *nats.Subscription
+	ended       bool
+	once        sync.Once
+}
+
+// CallStream implements client side call of a streaming function. The transporter timeout is applied as the longest
+// time allowed between two messages of the stream.
+func (n *NatsTransporter) CallStream(ctx context.Context, function string, requestData []byte) (yarf.StreamReceiver, error) {
+	if n.IsClose() {
+		return nil, errors.New("transport layer is has been closed")
+	}
+
+	if n.client.IsClosed() {
+		return nil, errors.New("nats transporter has been closed")
+	}
+
+	inbox := streamInboxPrefix + nuid.Next()
+	sub, err := n.client.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	n.incCount()
+	ctx, cancel := context.WithCancel(ctx)
+
+	com := n.fromFunction(n.namespace + streamPrefix + function)
+
+	go func() {
+		<-ctx.Done()
+		n.client.Publish(com.ctrl, []byte(ctrlCancel))
+	}()
+
+	r := &natsStreamReceiver{
+		transporter: n,
+		ctx:         ctx,
+		cancel:      cancel,
+		sub:         sub,
+	}
+
+	sendCtx, sendCancel := context.WithTimeout(ctx, n.timeout)
+	defer sendCancel()
+
+	err = com.send(sendCtx, append([]byte(inbox), requestData...))
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Recv implements yarf.StreamReceiver
+func (r *natsStreamReceiver) Recv() ([]byte, error) {
+	if r.ended {
+		return nil, io.EOF
+	}
+
+	data, err := r.transporter.receiveFrames(r.ctx, r.sub)
+	if err == io.EOF {
+		r.ended = true
+	}
+	return data, err
+}
+
+// Close implements yarf.StreamReceiver
+func (r *natsStreamReceiver) Close() error {
+	var err error
+	r.once.Do(func() {
+		r.cancel()
+		err = r.sub.Unsubscribe()
+		r.transporter.decCount()
+	})
+	return err
+}
+
+// receiveFrames receives the frames of the next message on the subscription, acknowledging each of them. io.EOF is
+// returned once the sender has ended the stream
+func (n *NatsTransporter) receiveFrames(ctx context.Context, sub *nats.Subscription) ([]byte, error) {
+	var data []byte
+	for {
+		timeoutCtx, cancel := context.WithTimeout(ctx, n.timeout)
+		msg, err := sub.NextMsgWithContext(timeoutCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+
+		if len(msg.Data) == 0 {
+			return nil, errors.New("received an empty stream frame")
+		}
+
+		switch msg.Data[0] {
+		case frameEnd:
+			return nil, io.EOF
+		case framePartial:
+			data = append(data, msg.Data[1:]...)
+			err = msg.Respond(ack)
+		case frameData:
+			err = msg.Respond(ack)
+			if data == nil {
+				return msg.Data[1:], err
+			}
+			return append(data, msg.Data[1:]...), err
+		default:
+			return nil, errors.New("received an unknown stream frame")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sendFrames sends data as one or more frames to the inbox, waiting for each frame to be acknowledged
+func (n *NatsTransporter) sendFrames(ctx context.Context, inbox string, data []byte) error {
+	size := int(n.client.MaxPayload()) - 1
+
+	for {
+		frameType := frameData
+		chunk := data
+		if len(data) > size {
+			frameType = framePartial
+			chunk = data[:size]
+		}
+
+		frame := make([]byte, 0, len(chunk)+1)
+		frame = append(frame, frameType)
+		frame = append(frame, chunk...)
+
+		ackCtx, cancel := context.WithTimeout(ctx, n.timeout)
+		_, err := n.client.RequestWithContext(ackCtx, inbox, frame)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if frameType == frameData {
+			return nil
+		}
+		data = data[size:]
+	}
+}
+
+// ListenStream defines the function that will handle yarf streaming requests
+func (n *NatsTransporter) ListenStream(function string, toExec func(ctx context.Context, requestData []byte, send func(data []byte) error) (responseData []byte)) error {
+
+	if n.IsClose() {
+		return errors.New("transport layer is has been closed")
+	}
+
+	queueGroup := function
+	parts := strings.Split(function, ".")
+	if len(parts) > 1 {
+		serverNamespaces := parts[:len(parts)-1]
+		queueGroup = strings.Join(serverNamespaces, ".")
+	}
+
+	function = n.namespace + streamPrefix + function
+	queueGroup = n.namespace + queueGroup
+
+	sub, err0 := n.client.QueueSubscribe(function, queueGroup, func(m *nats.Msg) {
+		go func() {
+			n.incCount()
+			defer n.decCount()
+			com := n.fromMessage(m)
+
+			ctx, cancel := com.contextCanceler()
+			defer cancel()
+
+			data, err := com.receive(ctx)
+			if err != nil {
+				n.log().Error("could not receive request", "function", function, "error", err)
+				return
+			}
+
+			// Acknowledging that the stream is opened, unless the request was upgraded to multipart
+			if !com.upgraded {
+				err = m.Respond(ack)
+				if err != nil {
+					n.log().Error("could not open stream", "function", function, "error", err)
+					return
+				}
+			}
+
+			if len(data) < ctrlHeaderLen {
+				n.log().Error("could not receive request, stream inbox was not provided", "function", function)
+				return
+			}
+			inbox, requestData := string(data[:ctrlHeaderLen]), data[ctrlHeaderLen:]
+
+			var mu sync.Mutex
+			send := func(data []byte) error {
+				mu.Lock()
+				defer mu.Unlock()
+				return n.sendFrames(ctx, inbox, data)
+			}
+
+			responseData := toExec(ctx, requestData, send)
+
+			err = send(responseData)
+			if err != nil {
+				n.log().Error("could not send response", "function", function, "error", err)
+				return
+			}
+
+			err = n.client.Publish(inbox, []byte{frameEnd})
+			if err != nil {
+				n.log().Error("could not end stream", "function", function, "error", err)
+				return
+			}
+		}()
+	})
+
+	n.mu.Lock()
+	n.subs = append(n.subs, sub)
+	n.mu.Unlock()
+
+	return err0
+}
@@ -3,8 +3,9 @@ package tnats
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/modfin/yarf"
 	"github.com/nats-io/nats.go"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +19,7 @@ type NatsTransporter struct {
 
 	timeout time.Duration
 	client  *nats.Conn
+	logger  *slog.Logger
 
 	mu     sync.Mutex
 	count  int64
@@ -45,6 +47,19 @@ func NewNatsTransporter(servers string, timeout time.Duration, opts ...nats.Opti
 	return &t, nil
 }
 
+// WithLogger sets the logger used to report failures while serving requests, defaults to yarf.Logger()
+func (n *NatsTransporter) WithLogger(logger *slog.Logger) *NatsTransporter {
+	n.logger = logger
+	return n
+}
+
+func (n *NatsTransporter) log() *slog.Logger {
+	if n.logger != nil {
+		return n.logger
+	}
+	return yarf.Logger()
+}
+
 // NewNatsTransporterFromConn a constructor for the NatsTransporter using an existing nats connection
 func NewNatsTransporterFromConn(natsConnection *nats.Conn, timeout time.Duration) (*NatsTransporter, error) {
 	t := NatsTransporter{
@@ -140,7 +155,7 @@ func (n *NatsTransporter) Call(ctx context.Context, function string, requestData
 	}
 
 	// TODO if "Did not get messages in time nats: timeout" context does not seam to be canceled correctly after timeout ....
-	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	ctx, cancel := context.WithTimeout(ctx, callTimeout(ctx, n.timeout))
 	defer cancel()
 
 	function = n.namespace + function
@@ -191,7 +206,7 @@ func (n *NatsTransporter) Listen(function string, toExec func(ctx context.Contex
 
 			requestData, err := com.receive(ctx)
 			if err != nil {
-				fmt.Println("Could not receive ", err)
+				n.log().Error("could not receive request", "function", function, "error", err)
 				return
 			}
 
@@ -199,7 +214,7 @@ func (n *NatsTransporter) Listen(function string, toExec func(ctx context.Contex
 
 			err = com.send(ctx, responseData)
 			if err != nil {
-				fmt.Println("Could not send ", err)
+				n.log().Error("could not send response", "function", function, "error", err)
 				return
 			}
 		}()
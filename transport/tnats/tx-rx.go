@@ -2,7 +2,6 @@ package tnats
 
 import (
 	"context"
-	"fmt"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nuid"
 )
@@ -65,7 +64,7 @@ func (t *txrx) contextCanceler() (context.Context, func()) {
 		defer cancel()
 
 		if err != nil {
-			fmt.Println("contextCanceler err 1", err)
+			t.transporter.log().Error("could not subscribe to control subject", "subject", t.ctrl, "error", err)
 		}
 
 		for {
@@ -3,7 +3,6 @@ package tnats
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/nats-io/nats.go"
 
 	"github.com/nats-io/nuid"
@@ -16,7 +15,7 @@ func (n *NatsTransporter) acceptUpgrade(m *nats.Msg) (tx string, rx string, err
 
 	cmd := strings.Split(string(m.Data), " ")
 	if len(cmd) != 2 {
-		fmt.Println("Fail do upgrade, All headers was not provided")
+		n.log().Error("could not upgrade to multipart, all headers were not provided", "subject", m.Subject)
 		return
 	}
 
@@ -25,7 +24,7 @@ func (n *NatsTransporter) acceptUpgrade(m *nats.Msg) (tx string, rx string, err
 
 	err = n.client.Publish(m.Reply, []byte("OK"))
 	if err != nil {
-		fmt.Println("Could not send OK")
+		n.log().Error("could not acknowledge upgrade to multipart", "subject", m.Subject, "error", err)
 		return
 	}
 
@@ -108,17 +107,16 @@ func (n *NatsTransporter) sendMultipart(channel string, data []byte) (err error)
 func (n *NatsTransporter) receiveMultipart(channel string) (data []byte, err error) {
 
 	sub, err := n.client.SubscribeSync(channel)
-	defer func() {
-		err = sub.Unsubscribe()
-		if err != nil {
-			fmt.Println("Could not unsubscribe")
-		}
-
-	}()
 	if err != nil {
-		fmt.Println("Could not send Subscribe")
+		n.log().Error("could not subscribe to multipart subject", "subject", channel, "error", err)
 		return
 	}
+	defer func() {
+		err2 := sub.Unsubscribe()
+		if err2 != nil {
+			n.log().Warn("could not unsubscribe from multipart subject", "subject", channel, "error", err2)
+		}
+	}()
 
 	waitChan := make(chan bool, 2)
 	errorsChan := make(chan error)
@@ -129,7 +127,7 @@ func (n *NatsTransporter) receiveMultipart(channel string) (data []byte, err err
 		resv := func() {
 			msg, err := sub.NextMsg(n.timeout)
 			if err != nil {
-				fmt.Println("Did not get messages in time", err)
+				n.log().Warn("did not receive multipart frame in time", "subject", channel, "error", err)
 				errorsChan <- err
 				return
 			}
@@ -0,0 +1,80 @@
+package yarf
+
+import "context"
+
+type contextKey int
+
+const (
+	requestMsgKey contextKey = iota
+	responseMsgKey
+	requestIDKey
+	explicitTimeoutKey
+)
+
+// RequestFromContext returns the request message of a handler registered using HandleTyped, giving access to params
+// and headers of the request
+func RequestFromContext(ctx context.Context) (msg *Msg, ok bool) {
+	msg, ok = ctx.Value(requestMsgKey).(*Msg)
+	return
+}
+
+// ResponseFromContext returns the response message of a handler registered using HandleTyped, making it possible to
+// set params and headers of the response
+func ResponseFromContext(ctx context.Context) (msg *Msg, ok bool) {
+	msg, ok = ctx.Value(responseMsgKey).(*Msg)
+	return
+}
+
+// HandleTyped creates a server endpoint for yarf, like Server.Handle, where the content of the request is bound to Req
+// and the value of Resp returned by fn is set as the content of the response. The request and response messages are
+// available from the context passed to fn using RequestFromContext and ResponseFromContext.
+func HandleTyped[Req, Resp any](s *Server, function string, fn func(ctx context.Context, req Req) (Resp, error), middleware ...Middleware) {
+	s.Handle(function, func(request *Msg, response *Msg) error {
+		var req Req
+
+		// Requests without content, e.g. made using only params, are passed on as the zero value
+		if _, ok := request.ContentType(); ok {
+			err := request.BindContent(&req)
+			if err != nil {
+				return NewRPCError(StatusUnmarshalError, err.Error())
+			}
+		}
+
+		ctx := request.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = context.WithValue(ctx, requestMsgKey, request)
+		ctx = context.WithValue(ctx, responseMsgKey, response)
+
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		response.SetContent(resp)
+		if response.builderError != nil {
+			return NewRPCError(StatusMarshalError, response.builderError.Error())
+		}
+		return nil
+	}, middleware...)
+
+	s.Describe(function, Function{Request: typeName[Req](), Response: typeName[Resp]()})
+}
+
+// CallTyped is a short hand, like Client.Call, that performs a request to function with req as content and params,
+// and returns the content of the response bound to Resp
+func CallTyped[Req, Resp any](c *Client, function string, req Req, params ...Param) (Resp, error) {
+	resp, _, err := GetTyped[Resp](c.Request(function).
+		WithParams(params...).
+		WithContent(req))
+	return resp, err
+}
+
+// GetTyped performs a request built using the RPC builder, waits for it to be done and returns the content of the
+// response bound to Resp along with the response message, giving access to params and headers of the response
+func GetTyped[Resp any](r *RPC) (Resp, *Msg, error) {
+	var resp Resp
+	msg, err := r.BindResponseContent(&resp).Get()
+	return resp, msg, err
+}
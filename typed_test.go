@@ -0,0 +1,74 @@
+package yarf_test
+
+import (
+	"context"
+	"errors"
+	"github.com/modfin/yarf"
+	"github.com/modfin/yarf/transport/tmem"
+	"testing"
+)
+
+type addReq struct {
+	A int
+	B int
+}
+
+type addResp struct {
+	Sum int
+}
+
+func TestTyped(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "typed")
+	client := yarf.NewClient(transport)
+
+	yarf.HandleTyped(&server, "add", func(ctx context.Context, req addReq) (addResp, error) {
+		request, ok := yarf.RequestFromContext(ctx)
+		if !ok {
+			return addResp{}, errors.New("no request in context")
+		}
+		response, ok := yarf.ResponseFromContext(ctx)
+		if !ok {
+			return addResp{}, errors.New("no response in context")
+		}
+		response.SetParam("offset", request.Param("offset").IntOr(0))
+		return addResp{Sum: req.A + req.B + int(request.Param("offset").IntOr(0))}, nil
+	})
+
+	resp, err := yarf.CallTyped[addReq, addResp](&client, "typed.add", addReq{A: 5, B: 7})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if resp.Sum != 12 {
+		t.Fatal("expected sum to be 12, got", resp.Sum)
+	}
+
+	resp, msg, err := yarf.GetTyped[addResp](client.Request("typed.add").
+		WithContent(addReq{A: 5, B: 7}).
+		WithParam("offset", 10))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if resp.Sum != 22 {
+		t.Fatal("expected sum to be 22, got", resp.Sum)
+	}
+	if msg.Param("offset").IntOr(0) != 10 {
+		t.Fatal("expected offset param to be 10, got", msg.Param("offset").Value())
+	}
+}
+
+func TestTypedError(t *testing.T) {
+	transport, _ := tmem.NewMemTransporter(tmem.Options{})
+	server := yarf.NewServer(transport, "typed")
+	client := yarf.NewClient(transport)
+
+	yarf.HandleTyped(&server, "fail", func(ctx context.Context, req addReq) (addResp, error) {
+		return addResp{}, yarf.NewRPCError(600, "failed")
+	})
+
+	_, err := yarf.CallTyped[addReq, addResp](&client, "typed.fail", addReq{})
+	rerr, ok := err.(yarf.RPCError)
+	if !ok || rerr.Status != 600 {
+		t.Fatal("expected rpc error with status 600, got", err)
+	}
+}